@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxRuns is the number of publish runs the server keeps the result of.
+const maxRuns = 1000
+
+// Statuses of a publish run.
+const (
+	runQueued    = "queued"
+	runRunning   = "running"
+	runSucceeded = "succeeded"
+	runFailed    = "failed"
+)
+
+// publishRun is a single run of a job, as reported by the trigger API.
+type publishRun struct {
+	ID       string     `json:"id"`
+	Job      string     `json:"job"`
+	Trigger  string     `json:"trigger"`
+	Status   string     `json:"status"`
+	Commit   string     `json:"commit,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// track registers a queued run of the job, forgetting the oldest run when
+// more than maxRuns are kept.
+func (s *publishServer) track(job publishJob, trigger string) *publishRun {
+	s.runsLock.Lock()
+	defer s.runsLock.Unlock()
+
+	run := &publishRun{ID: randomUUID(), Job: job.Name, Trigger: trigger, Status: runQueued, Created: time.Now().UTC()}
+	s.runs[run.ID] = run
+	s.runOrder = append(s.runOrder, run.ID)
+
+	if len(s.runOrder) > maxRuns {
+		delete(s.runs, s.runOrder[0])
+		s.runOrder = s.runOrder[1:]
+	}
+
+	return run
+}
+
+// snapshot returns a copy of the run that is safe to use without the lock.
+func (s *publishServer) snapshot(run *publishRun) publishRun {
+	s.runsLock.Lock()
+	defer s.runsLock.Unlock()
+	return *run
+}
+
+// execute runs the job and records the outcome in run.
+func (s *publishServer) execute(run *publishRun, job publishJob) {
+	s.runsLock.Lock()
+	run.Status = runRunning
+	s.runsLock.Unlock()
+
+	commit, err := s.run(job)
+
+	s.runsLock.Lock()
+	defer s.runsLock.Unlock()
+
+	finished := time.Now().UTC()
+	run.Finished = &finished
+	run.Commit = commit
+
+	if err != nil {
+		run.Status, run.Error = runFailed, err.Error()
+		fmt.Fprintln(os.Stderr, msg(messageJobFailed, job.Name, run.Trigger, run.ID, err))
+		return
+	}
+
+	run.Status = runSucceeded
+	fmt.Println(msg(messageJobPublished, job.Name, run.Trigger, run.ID, target{Repository: job.Repository, Branch: job.Branch}))
+}
+
+// start queues a run of the job in the background.
+func (s *publishServer) start(job publishJob, trigger string) publishRun {
+	run := s.track(job, trigger)
+	go s.execute(run, job)
+	return s.snapshot(run)
+}
+
+// authenticated rejects requests without the configured API token.
+func (s *publishServer) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.APIToken)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// handleTriggerPublish starts a run of a configured job, optionally with a
+// different source ref or commit message.
+func (s *publishServer) handleTriggerPublish(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Job           string `json:"job"`
+		Ref           string `json:"ref"`
+		CommitMessage string `json:"commit_message"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024*1024)).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	for _, job := range s.jobs {
+		if job.Name != request.Job {
+			continue
+		}
+
+		if request.Ref != "" {
+			job.Ref = request.Ref
+		}
+		if request.CommitMessage != "" {
+			job.CommitMessage = request.CommitMessage
+		}
+
+		writeJSON(w, http.StatusAccepted, s.start(job, "api"))
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("unknown job '%s'", request.Job)})
+}
+
+// handleGetPublish reports the status and result of a run.
+func (s *publishServer) handleGetPublish(w http.ResponseWriter, r *http.Request) {
+	s.runsLock.Lock()
+	run, ok := s.runs[r.PathValue("id")]
+	s.runsLock.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown run"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.snapshot(run))
+}
+
+// handleListPublishes reports the kept runs, most recent first.
+func (s *publishServer) handleListPublishes(w http.ResponseWriter, r *http.Request) {
+	s.runsLock.Lock()
+	runs := make([]publishRun, 0, len(s.runOrder))
+	for i := len(s.runOrder) - 1; i >= 0; i-- {
+		runs = append(runs, *s.runs[s.runOrder[i]])
+	}
+	s.runsLock.Unlock()
+
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// writeJSON writes value as a JSON response with the status code.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
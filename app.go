@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+// appJWT returns a JSON web token authenticating as the GitHub App, valid for
+// the few minutes needed to request an installation token.
+func appJWT(appID, privateKey string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return "", fmt.Errorf("app private key is not PEM encoded")
+	}
+
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = parsed
+	} else {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse app private key: %w", err)
+		}
+
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("app private key is not an RSA key")
+		}
+		key = rsaKey
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	// The issued time is backdated to allow for clock drift with GitHub.
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign app token: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// installationToken mints an installation access token for the GitHub App
+// installed on repository.
+func installationToken(cfg Config, repository string) (string, error) {
+	if cfg.AppPrivateKey == "" {
+		return "", fmt.Errorf("app_private_key is required with app_id")
+	}
+
+	jwt, err := appJWT(cfg.AppID, cfg.AppPrivateKey, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	var installation struct {
+		ID int64 `json:"id"`
+	}
+	if err := githubRequest(jwt, "GET", fmt.Sprintf("/repos/%s/installation", repository), nil, &installation); err != nil {
+		return "", fmt.Errorf("failed to find the app installation for %s: %w", repository, err)
+	}
+
+	var token struct {
+		Token string `json:"token"`
+	}
+	if err := githubRequest(jwt, "POST", fmt.Sprintf("/app/installations/%d/access_tokens", installation.ID), nil, &token); err != nil {
+		return "", fmt.Errorf("failed to create an installation token: %w", err)
+	}
+
+	fmt.Printf("::add-mask::%s\n", token.Token)
+	return token.Token, nil
+}
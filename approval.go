@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+)
+
+// errRejected is returned when an approver rejects the publish.
+var errRejected = errors.New("publish was rejected")
+
+// approvalPollInterval is how often reactions on the approval comment are read.
+const approvalPollInterval = 15 * time.Second
+
+type githubReaction struct {
+	Content string `json:"content"`
+	User    struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+// waitForApproval posts the planned changes as a comment on the issue or pull
+// request and blocks until an approver reacts with 👍 (approve) or 👎
+// (reject), or the timeout expires.
+func waitForApproval(cfg Config, changes string) error {
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	if repository == "" {
+		return fmt.Errorf("GITHUB_REPOSITORY environment variable not set")
+	}
+
+	number := cfg.ApprovalIssue
+	if number == 0 {
+		number = githubEventNumber()
+	}
+	if number == 0 {
+		return fmt.Errorf("no issue or pull request to request approval on, set approval_issue")
+	}
+
+	timeout, err := time.ParseDuration(cfg.ApprovalTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid approval timeout: %w", err)
+	}
+
+	body := fmt.Sprintf("**publish-directory** is waiting for approval to push to `%s`.\n\nReact with 👍 to approve or 👎 to reject.\n\n```\n%s```\n", cfg.Branch, changes)
+
+	var comment struct {
+		ID int64 `json:"id"`
+	}
+	if err := githubRequest(cfg.GithubToken, "POST", fmt.Sprintf("/repos/%s/issues/%d/comments", repository, number), map[string]string{"body": body}, &comment); err != nil {
+		return fmt.Errorf("failed to post approval request: %w", err)
+	}
+
+	fmt.Println(msg(messageWaitingForApproval, timeout, number))
+
+	approvers := splitList(cfg.Approvers)
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		var reactions []githubReaction
+		if err := githubRequest(cfg.GithubToken, "GET", fmt.Sprintf("/repos/%s/issues/comments/%d/reactions", repository, comment.ID), nil, &reactions); err != nil {
+			return fmt.Errorf("failed to read approval reactions: %w", err)
+		}
+
+		for _, reaction := range reactions {
+			if reaction.Content != "+1" && reaction.Content != "-1" {
+				continue
+			}
+
+			allowed, err := isApprover(cfg.GithubToken, repository, reaction.User.Login, approvers)
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				continue
+			}
+
+			if reaction.Content == "-1" {
+				return fmt.Errorf("%w by %s", errRejected, reaction.User.Login)
+			}
+
+			fmt.Println(msg(messageApproved, reaction.User.Login))
+			return nil
+		}
+
+		time.Sleep(approvalPollInterval)
+	}
+
+	return fmt.Errorf("no approval received within %s", timeout)
+}
+
+// isApprover reports whether the user may approve the publish: one of the
+// configured approvers, or anyone with write access when none are configured.
+func isApprover(token, repository, login string, approvers []string) (bool, error) {
+	if len(approvers) > 0 {
+		return slices.Contains(approvers, login), nil
+	}
+
+	var permission struct {
+		Permission string `json:"permission"`
+	}
+	if err := githubRequest(token, "GET", fmt.Sprintf("/repos/%s/collaborators/%s/permission", repository, url.PathEscape(login)), nil, &permission); err != nil {
+		return false, fmt.Errorf("failed to check permission of %s: %w", login, err)
+	}
+
+	return permission.Permission == "admin" || permission.Permission == "write", nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// githubUser is the subset of a GitHub user needed to build a commit identity.
+type githubUser struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+// actorIdentity resolves the GitHub actor that triggered the workflow into a
+// commit name and noreply email address, so the publish is attributed to them.
+func actorIdentity(token string) (string, string, error) {
+	actor := os.Getenv("GITHUB_ACTOR")
+	if actor == "" {
+		return "", "", fmt.Errorf("GITHUB_ACTOR environment variable not set")
+	}
+
+	var user githubUser
+	if err := githubRequest(token, "GET", "/users/"+url.PathEscape(actor), nil, &user); err != nil {
+		return "", "", fmt.Errorf("failed to look up actor '%s': %w", actor, err)
+	}
+
+	name := user.Name
+	if name == "" {
+		name = user.Login
+	}
+
+	return name, fmt.Sprintf("%d+%s@users.noreply.github.com", user.ID, user.Login), nil
+}
+
+// actorEnvironmentIdentity builds a commit identity for the GitHub actor that
+// triggered the workflow from the runner environment alone, without the API
+// lookup of their display name.
+func actorEnvironmentIdentity() (string, string, error) {
+	actor, actorID := os.Getenv("GITHUB_ACTOR"), os.Getenv("GITHUB_ACTOR_ID")
+	if actor == "" || actorID == "" {
+		return "", "", fmt.Errorf("GITHUB_ACTOR and GITHUB_ACTOR_ID environment variables not set")
+	}
+
+	return actor, fmt.Sprintf("%s+%s@users.noreply.github.com", actorID, actor), nil
+}
+
+// sourceCommit returns the commit the workflow runs for, GITHUB_SHA, or the
+// HEAD of the repository containing folder when it is not set.
+func sourceCommit(folder string) (*object.Commit, error) {
+	root, err := findRepositoryRoot(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := plumbing.NewHash(os.Getenv("GITHUB_SHA"))
+	if hash.IsZero() {
+		head, err := repo.Head()
+		if err != nil {
+			return nil, err
+		}
+		hash = head.Hash()
+	}
+
+	return repo.CommitObject(hash)
+}
+
+// commitSignatures returns the author and committer of the publish commit.
+// The committer is the author unless a separate committer is configured.
+func commitSignatures(cfg Config, when time.Time) (*object.Signature, *object.Signature) {
+	author := &object.Signature{Name: cfg.CommitUser, Email: cfg.CommitEmail, When: when}
+	if cfg.CommitterName == "" && cfg.CommitterEmail == "" {
+		return author, author
+	}
+
+	committer := &object.Signature{Name: cfg.CommitterName, Email: cfg.CommitterEmail, When: when}
+	if committer.Name == "" {
+		committer.Name = author.Name
+	}
+	if committer.Email == "" {
+		committer.Email = author.Email
+	}
+
+	return author, committer
+}
+
+// reproducibleTime reports whether the time of the publish commit is fixed by
+// the configuration rather than taken from the clock.
+func reproducibleTime(cfg Config) bool {
+	return cfg.CommitDate != "" || cfg.UseSourceDate || cfg.SourceDateEpoch != ""
+}
+
+// commitTime returns the timestamp for the publish commit: the configured
+// commit date, the author date of the source commit when configured,
+// SOURCE_DATE_EPOCH for reproducible builds, otherwise now, in the configured
+// timezone when one is set.
+func commitTime(cfg Config) (time.Time, error) {
+	when := time.Now()
+
+	switch {
+	case cfg.CommitDate != "":
+		date, err := parseCommitDate(cfg.CommitDate)
+		if err != nil {
+			return when, err
+		}
+		when = date
+	case cfg.UseSourceDate:
+		commit, err := sourceCommit(sourceFolders(cfg)[0].Path)
+		if err != nil {
+			return when, fmt.Errorf("failed to read source commit: %w", err)
+		}
+		when = commit.Author.When
+	case cfg.SourceDateEpoch != "":
+		date, err := parseCommitDate(cfg.SourceDateEpoch)
+		if err != nil {
+			return when, fmt.Errorf("invalid SOURCE_DATE_EPOCH: %w", err)
+		}
+		when = date
+	}
+
+	if cfg.CommitTimezone == "" {
+		return when, nil
+	}
+
+	location, err := time.LoadLocation(cfg.CommitTimezone)
+	if err != nil {
+		return when, fmt.Errorf("invalid commit timezone '%s': %w", cfg.CommitTimezone, err)
+	}
+
+	return when.In(location), nil
+}
+
+// parseCommitDate parses a commit date given in seconds since the Unix epoch
+// or in RFC 3339 format. Epoch dates are in UTC.
+func parseCommitDate(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("commit date '%s' is neither seconds since the epoch nor RFC 3339", value)
+	}
+	return date, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// azureProvider publishes to Azure DevOps Repos, addressed as
+// organization/project/repository.
+type azureProvider struct{}
+
+func (azureProvider) remote(cfg Config, repository string) (string, transport.AuthMethod, error) {
+	remoteURL := repository
+	if !strings.Contains(repository, "://") {
+		segments := strings.Split(repository, "/")
+		if len(segments) != 3 {
+			return "", nil, fmt.Errorf("azure devops repository '%s' must be given as organization/project/repository", repository)
+		}
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+
+		base := strings.TrimSuffix(cfg.AzureDevOpsURL, "/")
+		remoteURL = fmt.Sprintf("%s/%s/%s/_git/%s", base, segments[0], segments[1], segments[2])
+	}
+
+	// Azure Pipelines exposes the token of the job when mapped into the
+	// environment of the step.
+	token := cfg.AzureDevOpsToken
+	if token == "" {
+		token = os.Getenv("SYSTEM_ACCESSTOKEN")
+	}
+
+	// Personal access tokens are sent as the password of an empty user name.
+	return remoteURL, &http.BasicAuth{Password: token}, nil
+}
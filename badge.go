@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// badge is a shields.io endpoint badge, see https://shields.io/badges/endpoint-badge.
+type badge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// writeBadge writes a shields.io endpoint badge describing the publish to name,
+// relative to dir. The message is the version when given, otherwise the time
+// of the publish.
+func writeBadge(dir, name, label, version string, published time.Time) error {
+	message := version
+	if message == "" {
+		message = published.UTC().Format("2006-01-02 15:04 UTC")
+	}
+
+	data, err := json.MarshalIndent(badge{
+		SchemaVersion: 1,
+		Label:         label,
+		Message:       message,
+		Color:         "brightgreen",
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// bitbucketProvider publishes to Bitbucket Cloud repositories, addressed as
+// workspace/repository.
+type bitbucketProvider struct{}
+
+func (bitbucketProvider) remote(cfg Config, repository string) (string, transport.AuthMethod, error) {
+	// App passwords authenticate as the user owning them, while repository,
+	// project and workspace access tokens use a fixed user name.
+	username := cfg.BitbucketUsername
+	if username == "" {
+		username = "x-token-auth"
+	}
+
+	return providerURL("https://bitbucket.org", repository), &http.BasicAuth{
+		Username: username,
+		Password: cfg.BitbucketToken,
+	}, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// pushAdditionalBranches publishes the tree prepared for the branch to each
+// of the additional branches as well, fetching their tips into the same
+// repository instead of cloning and copying the content again. The commits are
+// created from the staged index, so they share the published tree. HEAD is
+// restored to the branch afterwards.
+func pushAdditionalBranches(cfg Config, repo *git.Repository, auth transport.AuthMethod, repository, message string, orphan bool) error {
+	if len(cfg.AdditionalBranches) == 0 {
+		return nil
+	}
+
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(cfg.Branch))
+	defer repo.Storer.SetReference(head)
+
+	published, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve published commit: %w", err)
+	}
+
+	publishedCommit, err := repo.CommitObject(published.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to read published commit: %w", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	when, err := commitTime(cfg)
+	if err != nil {
+		return err
+	}
+
+	signKey, err := gpgSignKey(cfg)
+	if err != nil {
+		return err
+	}
+
+	signer, err := sshSigner(cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, branch := range cfg.AdditionalBranches {
+		branchRef := plumbing.NewBranchReferenceName(branch)
+		remoteRef := plumbing.NewRemoteReferenceName("origin", branch)
+
+		err := repo.Fetch(&git.FetchOptions{
+			RemoteName: "origin",
+			RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", branchRef, remoteRef))},
+			Auth:       auth,
+			Depth:      1,
+		})
+		if err != nil && err != git.NoErrAlreadyUpToDate && !isMissingBranch(err) {
+			return fmt.Errorf("failed to fetch branch '%s': %w", branch, err)
+		}
+
+		// The commit extends the tip of the branch, or starts it when the
+		// branch is new or its history is replaced.
+		previous := plumbing.ZeroHash
+		if err := repo.Storer.RemoveReference(branchRef); err != nil {
+			return fmt.Errorf("failed to reset branch '%s': %w", branch, err)
+		}
+		if tip, err := repo.Reference(remoteRef, true); err == nil {
+			previous = tip.Hash()
+
+			tipCommit, err := repo.CommitObject(previous)
+			if err != nil {
+				return fmt.Errorf("failed to read tip of branch '%s': %w", branch, err)
+			}
+
+			if tipCommit.TreeHash == publishedCommit.TreeHash && cfg.SkipEmptyCommits && !orphan {
+				fmt.Println(msg(messageBranchUpToDate, branch))
+				continue
+			}
+
+			if !orphan {
+				if err := repo.Storer.SetReference(plumbing.NewHashReference(branchRef, previous)); err != nil {
+					return fmt.Errorf("failed to check out branch '%s': %w", branch, err)
+				}
+			}
+		}
+
+		if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branchRef)); err != nil {
+			return fmt.Errorf("failed to check out branch '%s': %w", branch, err)
+		}
+
+		author, committer := commitSignatures(cfg, when)
+		commit, err := worktree.Commit(message, &git.CommitOptions{
+			Author:            author,
+			Committer:         committer,
+			AllowEmptyCommits: true,
+			SignKey:           signKey,
+			Signer:            signer,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to commit to branch '%s': %w", branch, err)
+		}
+
+		push := func() error {
+			stop := spinner(msg(messagePushing, branch))
+			defer stop()
+
+			return repo.Push(&git.PushOptions{
+				RemoteName: "origin",
+				RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("%s:%s", branchRef, branchRef))},
+				Auth:       auth,
+				Progress:   progressWriter(),
+				Force:      orphan || cfg.Force,
+			})
+		}
+
+		if err := retryPush(cfg, push); err != nil && err != git.NoErrAlreadyUpToDate {
+			return fmt.Errorf("failed to push branch '%s': %w", branch, err)
+		}
+
+		record := publishRecord{Repository: repository, Branch: branch, Previous: previous.String(), Published: commit.String()}
+		if err := recordPublish(record); err != nil {
+			return fmt.Errorf("failed to record publish: %w", err)
+		}
+	}
+
+	return nil
+}
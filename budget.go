@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits maps the suffixes accepted in size inputs to their multipliers.
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"GB", 1024 * 1024 * 1024},
+	{"MB", 1024 * 1024},
+	{"KB", 1024},
+	{"B", 1},
+}
+
+// parseSize parses a human readable size such as '500MB' or '2 GB' into bytes.
+func parseSize(input string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(input))
+
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", input)
+	}
+
+	return int64(number * float64(multiplier)), nil
+}
+
+// formatSize renders a size in bytes in the largest fitting unit.
+func formatSize(size int64) string {
+	for _, unit := range sizeUnits {
+		if size >= unit.multiplier && unit.multiplier > 1 {
+			return fmt.Sprintf("%.1f %s", float64(size)/float64(unit.multiplier), unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+// objectStoreSize estimates the packed size of a repository by summing the
+// size of its object store on disk.
+func objectStoreSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.Walk(filepath.Join(dir, ".git", "objects"), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return size, err
+}
+
+// checkSizeBudget compares the estimated size of the branch against the
+// configured budget, warning or failing when it is exceeded.
+func checkSizeBudget(cfg Config, dir string) error {
+	exceeded, message, err := exceedsSizeBudget(cfg, dir)
+	if err != nil || !exceeded {
+		return err
+	}
+
+	if cfg.SizeBudgetAction == "fail" {
+		return fmt.Errorf("%s", message)
+	}
+
+	fmt.Printf("::warning title=%s::%s\n", msg(messageSizeBudgetExceeded), message)
+	return nil
+}
+
+// exceedsSizeBudget estimates the size of the branch cloned into dir and
+// reports whether it exceeds the budget, with a message describing by how much.
+func exceedsSizeBudget(cfg Config, dir string) (bool, string, error) {
+	budget, err := parseSize(cfg.SizeBudget)
+	if err != nil {
+		return false, "", err
+	}
+
+	size, err := objectStoreSize(dir)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to estimate branch size: %w", err)
+	}
+
+	if err := setOutput("branch_size", strconv.FormatInt(size, 10)); err != nil {
+		return false, "", err
+	}
+
+	fmt.Println(msg(messageBranchSize, formatSize(size), formatSize(budget)))
+
+	if size <= budget {
+		return false, "", nil
+	}
+
+	return true, fmt.Sprintf("branch '%s' is estimated at %s, exceeding its budget of %s; consider squashing its history", cfg.Branch, formatSize(size), formatSize(budget)), nil
+}
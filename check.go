@@ -0,0 +1,269 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// checkBranch validates the health of the published branch: that it can be
+// reached, that its tip and tree parse, that the tip was created by the action
+// and, when an SBOM is published, that the tree matches it.
+func checkBranch(cfg Config) error {
+	_, url, auth, err := resolveRemote(cfg)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	report := func(ok bool, key string, args ...any) {
+		message := msg(key, args...)
+		if ok {
+			fmt.Println(msg(messageCheckPassed, message))
+			return
+		}
+		fmt.Println(msg(messageCheckFailed, message))
+		problems = append(problems, message)
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{url}})
+	references, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return fmt.Errorf("remote is not reachable: %w", err)
+	}
+
+	branchReference := plumbing.NewBranchReferenceName(cfg.Branch)
+	found := false
+	for _, reference := range references {
+		if reference.Name() == branchReference {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("branch '%s' does not exist on the remote", cfg.Branch)
+	}
+	report(true, messageCheckReachable, cfg.Branch)
+
+	repo, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
+		URL:           url,
+		Auth:          auth,
+		ReferenceName: branchReference,
+		SingleBranch:  true,
+		Depth:         1,
+		NoCheckout:    true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch branch: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve branch tip: %w", err)
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		report(false, messageCheckTipInvalid, head.Hash(), err)
+		return checkResult(problems)
+	}
+	report(true, messageCheckTip, commit.Hash)
+
+	tree, err := commit.Tree()
+	if err != nil {
+		report(false, messageCheckTreeInvalid, commit.Hash, err)
+		return checkResult(problems)
+	}
+
+	files := map[string]*object.File{}
+	err = tree.Files().ForEach(func(file *object.File) error {
+		files[file.Name] = file
+		return nil
+	})
+	report(err == nil, messageCheckTree, tree.Hash, len(files))
+
+	report(hasTrailer(commit.Message, publishTrailer), messageCheckPublished)
+
+	if cfg.SBOM != "" {
+		checkTreeAgainstSBOM(files, sbomPath(cfg), generatedPaths(cfg), report)
+	}
+
+	return checkResult(problems)
+}
+
+func checkResult(problems []string) error {
+	if err := setOutput("healthy", fmt.Sprintf("%t", len(problems) == 0)); err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%d checks failed", len(problems))
+	}
+
+	fmt.Println(msg(messageBranchHealthy))
+	return nil
+}
+
+// checkTreeAgainstSBOM compares the files in the tree with the SHA-256 hashes
+// recorded in the published SBOM. LFS pointers are compared by their oid.
+func checkTreeAgainstSBOM(files map[string]*object.File, path string, generated []string, report func(bool, string, ...any)) {
+	sbom, ok := files[path]
+	if !ok {
+		report(false, messageCheckSBOMPresent, path)
+		return
+	}
+
+	content, err := sbom.Contents()
+	if err != nil {
+		report(false, messageCheckSBOMReadable, path, err)
+		return
+	}
+
+	recorded, err := parseSBOMHashes([]byte(content))
+	if err != nil {
+		report(false, messageCheckSBOMParses, path, err)
+		return
+	}
+
+	mismatches := 0
+	for name, expected := range recorded {
+		file, ok := files[name]
+		if !ok {
+			report(false, messageCheckSBOMMissing, name)
+			mismatches++
+			continue
+		}
+
+		actual, err := fileSHA256(file)
+		if err != nil || actual != expected {
+			report(false, messageCheckSBOMMismatch, name)
+			mismatches++
+		}
+	}
+
+	for name := range files {
+		if _, ok := recorded[name]; ok || name == path {
+			continue
+		}
+
+		isGenerated := false
+		for _, generatedPath := range generated {
+			isGenerated = isGenerated || name == generatedPath
+		}
+
+		if !isGenerated {
+			report(false, messageCheckSBOMUnlisted, name)
+			mismatches++
+		}
+	}
+
+	if mismatches == 0 {
+		report(true, messageCheckSBOM, len(recorded), path)
+	}
+}
+
+// parseSBOMHashes extracts the SHA-256 hash of every file from a CycloneDX or
+// SPDX document generated by writeSBOM.
+func parseSBOMHashes(data []byte) (map[string]string, error) {
+	var document struct {
+		Components []struct {
+			Name   string `json:"name"`
+			Hashes []struct {
+				Alg     string `json:"alg"`
+				Content string `json:"content"`
+			} `json:"hashes"`
+		} `json:"components"`
+		Files []struct {
+			FileName  string `json:"fileName"`
+			Checksums []struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"checksumValue"`
+			} `json:"checksums"`
+		} `json:"files"`
+	}
+
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	hashes := map[string]string{}
+	for _, component := range document.Components {
+		for _, hash := range component.Hashes {
+			if hash.Alg == "SHA-256" {
+				hashes[component.Name] = hash.Content
+			}
+		}
+	}
+
+	for _, file := range document.Files {
+		for _, checksum := range file.Checksums {
+			if checksum.Algorithm == "SHA256" {
+				hashes[strings.TrimPrefix(file.FileName, "./")] = checksum.Value
+			}
+		}
+	}
+
+	return hashes, nil
+}
+
+// fileSHA256 returns the SHA-256 of a file's content, or the oid of the object
+// it points to when it is an LFS pointer.
+func fileSHA256(file *object.File) (string, error) {
+	reader, err := file.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	var head strings.Builder
+	if _, err := io.Copy(io.MultiWriter(hash, &limitedBuilder{&head, 1024}), reader); err != nil {
+		return "", err
+	}
+
+	if oid, ok := lfsPointerOid(head.String()); ok && file.Size < 1024 {
+		return oid, nil
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// lfsPointerOid returns the oid of an LFS pointer file.
+func lfsPointerOid(content string) (string, bool) {
+	if !strings.HasPrefix(content, "version https://git-lfs.github.com/spec/v1\n") {
+		return "", false
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if oid, found := strings.CutPrefix(line, "oid sha256:"); found {
+			return oid, true
+		}
+	}
+
+	return "", false
+}
+
+// limitedBuilder keeps the first limit bytes written to it and discards the rest.
+type limitedBuilder struct {
+	builder *strings.Builder
+	limit   int
+}
+
+func (b *limitedBuilder) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.builder.Len(); remaining > 0 {
+		if len(p) < remaining {
+			remaining = len(p)
+		}
+		b.builder.Write(p[:remaining])
+	}
+	return len(p), nil
+}
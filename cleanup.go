@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// cleanupBranch deletes the published branch from the remote, typically a
+// preview branch of a pull request that was closed. A branch that does not
+// exist is left alone.
+func cleanupBranch(cfg Config) error {
+	repository, url, auth, err := resolveRemote(cfg)
+	if err != nil {
+		return err
+	}
+
+	if !cfg.AllowSelfPublish && isWorkflowBranch(repository, cfg.Branch) {
+		return fmt.Errorf("%w: '%s' in %s", errSelfPublish, cfg.Branch, repository)
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{url}})
+	references, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return fmt.Errorf("failed to list remote branches: %w", err)
+	}
+
+	branchReference := plumbing.NewBranchReferenceName(cfg.Branch)
+	for _, reference := range references {
+		if reference.Name() == branchReference {
+			return deleteRemoteBranches(cfg, remote, auth, []string{cfg.Branch})
+		}
+	}
+
+	fmt.Println(msg(messageNothingToCleanUp, cfg.Branch))
+	return nil
+}
+
+// deleteRemoteBranches deletes the branches from the remote in a single push.
+func deleteRemoteBranches(cfg Config, remote *git.Remote, auth transport.AuthMethod, branches []string) error {
+	var refSpecs []config.RefSpec
+	for _, branch := range branches {
+		fmt.Println(msg(messageDeletingBranch, branch))
+		refSpecs = append(refSpecs, config.RefSpec(":"+plumbing.NewBranchReferenceName(branch).String()))
+	}
+
+	err := retryPush(cfg, func() error {
+		return remote.Push(&git.PushOptions{RemoteName: "origin", Auth: auth, RefSpecs: refSpecs})
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to delete branches: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// pullRequestComment is an issue comment, as returned by the comments API.
+type pullRequestComment struct {
+	ID   int64  `json:"id"`
+	Body string `json:"body"`
+}
+
+// isPullRequestEvent reports whether the workflow was triggered by a pull
+// request.
+func isPullRequestEvent() bool {
+	event := os.Getenv("GITHUB_EVENT_NAME")
+	return event == "pull_request" || event == "pull_request_target"
+}
+
+// commentOnPullRequest posts, or updates on later runs, a comment on the pull
+// request that triggered the workflow linking to the published branch and the
+// preview. Runs for other events are skipped.
+func commentOnPullRequest(cfg Config, repository, commit string, changes changeStats) error {
+	number := githubEventNumber()
+	if !isPullRequestEvent() || number == 0 {
+		return nil
+	}
+
+	source := os.Getenv("GITHUB_REPOSITORY")
+	marker := fmt.Sprintf("<!-- publish-directory:%s -->", target{Repository: repository, Branch: cfg.Branch})
+
+	var builder strings.Builder
+	branchURL := fmt.Sprintf("%s/%s/tree/%s", githubServerURL(), repository, url.PathEscape(cfg.Branch))
+	fmt.Fprintf(&builder, "%s\n%s\n\n", marker, msg(messageCommentPublished, cfg.Branch, branchURL, commit[:min(len(commit), 7)]))
+	if cfg.PreviewURL != "" {
+		preview := expandTemplate(strings.NewReplacer("{branch}", cfg.Branch, "{repository}", repository).Replace(cfg.PreviewURL), time.Now())
+		fmt.Fprintf(&builder, "%s\n\n", msg(messageCommentPreview, preview))
+	}
+	fmt.Fprintln(&builder, msg(messageCommentChanges, changes.Added, changes.Modified, changes.Deleted))
+
+	comments, err := githubList[pullRequestComment](cfg.GithubToken, fmt.Sprintf("/repos/%s/issues/%d/comments?per_page=100", source, number))
+	if err != nil {
+		return fmt.Errorf("failed to read pull request comments: %w", err)
+	}
+
+	body := map[string]string{"body": builder.String()}
+	for _, comment := range comments {
+		if strings.HasPrefix(comment.Body, marker) {
+			if err := githubRequest(cfg.GithubToken, "PATCH", fmt.Sprintf("/repos/%s/issues/comments/%d", source, comment.ID), body, nil); err != nil {
+				return fmt.Errorf("failed to update pull request comment: %w", err)
+			}
+			return nil
+		}
+	}
+
+	if err := githubRequest(cfg.GithubToken, "POST", fmt.Sprintf("/repos/%s/issues/%d/comments", source, number), body, nil); err != nil {
+		return fmt.Errorf("failed to comment on pull request: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// commitStatusContext identifies the statuses posted by the action.
+const commitStatusContext = "publish-directory"
+
+// setSourceStatus posts a successful commit status on the commit that
+// triggered the workflow, linking to the branch the folder was published to.
+func setSourceStatus(cfg Config, repository, commit string) error {
+	source, sha := os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_SHA")
+	if source == "" || sha == "" {
+		return fmt.Errorf("GITHUB_REPOSITORY and GITHUB_SHA environment variables must be set to set a commit status")
+	}
+
+	body := map[string]string{
+		"state":       "success",
+		"context":     commitStatusContext,
+		"description": fmt.Sprintf("published to %s as %s", cfg.Branch, commit[:min(len(commit), 7)]),
+		"target_url":  fmt.Sprintf("%s/%s/tree/%s", githubServerURL(), repository, url.PathEscape(cfg.Branch)),
+	}
+
+	if err := githubRequest(cfg.GithubToken, "POST", fmt.Sprintf("/repos/%s/statuses/%s", source, sha), body, nil); err != nil {
+		return fmt.Errorf("failed to set commit status: %w", err)
+	}
+
+	return nil
+}
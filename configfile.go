@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// inputConflicts lists the inputs that cannot be set together.
+var inputConflicts = [][2]string{
+	{"gpg_private_key", "ssh_signing_key"},
+}
+
+// inputDependencies maps inputs to the input they require.
+var inputDependencies = map[string]string{
+	"aliases": "version",
+}
+
+// configFileError is a problem at a position in the config file.
+type configFileError struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e configFileError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Message)
+}
+
+// readConfigFile reads the inputs from the YAML config file at path, a mapping
+// of input names to values, after validating it against the input schema. All
+// problems are reported at once, with their line and column.
+func readConfigFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+
+	if len(document.Content) == 0 {
+		return map[string]string{}, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, configFileError{path, root.Line, root.Column, "expected a mapping of input names to values"}
+	}
+
+	schemas, _, err := inputSchemas()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []error
+	problem := func(node *yaml.Node, format string, args ...any) {
+		problems = append(problems, configFileError{path, node.Line, node.Column, fmt.Sprintf(format, args...)})
+	}
+
+	inputs := map[string]string{}
+	keys := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		name := key.Value
+
+		schema, known := schemas[name]
+		switch {
+		case !known || name == "config_file":
+			problem(key, "unknown input '%s'", name)
+			continue
+		case keys[name] != nil:
+			problem(key, "input '%s' is already set on line %d", name, keys[name].Line)
+			continue
+		}
+		keys[name] = key
+
+		input, err := configFileValue(value, schema)
+		if err != nil {
+			problem(value, "input '%s': %v", name, err)
+			continue
+		}
+		inputs[name] = input
+	}
+
+	for _, conflict := range inputConflicts {
+		if keys[conflict[0]] != nil && keys[conflict[1]] != nil {
+			problem(keys[conflict[1]], "input '%s' cannot be combined with '%s'", conflict[1], conflict[0])
+		}
+	}
+
+	for name, required := range inputDependencies {
+		if keys[name] != nil && keys[required] == nil {
+			problem(keys[name], "input '%s' requires '%s'", name, required)
+		}
+	}
+
+	if len(problems) > 0 {
+		slices.SortFunc(problems, func(a, b error) int {
+			return a.(configFileError).Line - b.(configFileError).Line
+		})
+		return nil, errors.Join(problems...)
+	}
+
+	return inputs, nil
+}
+
+// configFileValue converts a config file value to the string representation
+// of its input, checking it matches the input's type. Sequences are accepted
+// for string inputs and joined into a newline separated list.
+func configFileValue(node *yaml.Node, schema inputSchema) (string, error) {
+	if node.Kind == yaml.SequenceNode && schema.Type == "string" {
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("expected a list of strings")
+			}
+			items = append(items, item.Value)
+		}
+		return strings.Join(items, "\n"), nil
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("expected a %s", schema.Type)
+	}
+
+	switch tag := node.ShortTag(); {
+	case schema.Type == "boolean" && tag != "!!bool":
+		return "", fmt.Errorf("expected a boolean, got '%s'", node.Value)
+	case schema.Type == "integer" && tag != "!!int":
+		return "", fmt.Errorf("expected an integer, got '%s'", node.Value)
+	case tag == "!!null":
+		return "", nil
+	}
+
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, node.Value) {
+		return "", fmt.Errorf("expected one of %s, got '%s'", strings.Join(schema.Enum, ", "), node.Value)
+	}
+
+	return node.Value, nil
+}
+
+// applyConfigFile fills in the inputs of the environment from the config file
+// it names. Inputs set in the environment take precedence, unless they are
+// empty or hold their default value, as the runner sets every input that has
+// a default in the action metadata.
+func applyConfigFile(environment map[string]string) error {
+	path := environment["INPUT_CONFIG_FILE"]
+	if path == "" {
+		return nil
+	}
+
+	inputs, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+
+		variable := field.Tag.Get("env")
+		input, found := strings.CutPrefix(variable, "INPUT_")
+		if !found {
+			continue
+		}
+
+		value, ok := inputs[strings.ToLower(input)]
+		if !ok {
+			continue
+		}
+
+		if current := environment[variable]; current != "" && current != field.Tag.Get("envDefault") {
+			continue
+		}
+		environment[variable] = value
+	}
+
+	return nil
+}
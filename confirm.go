@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// errAborted is returned when the user declines a destructive operation.
+var errAborted = errors.New("aborted by user")
+
+// isInteractive reports whether the action runs as a CLI on a terminal rather
+// than in CI, where nobody could answer a prompt.
+func isInteractive() bool {
+	if os.Getenv("CI") != "" || os.Getenv("GITHUB_ACTIONS") != "" {
+		return false
+	}
+
+	return isTerminal(os.Stdin)
+}
+
+// isTerminal reports whether file is a terminal.
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks the user to confirm a destructive operation. It returns
+// errAborted when declined, and passes without asking when --yes was given or
+// the action does not run interactively.
+func confirm(cfg Config, question string) error {
+	if cfg.AssumeYes || !isInteractive() {
+		return nil
+	}
+
+	fmt.Printf("%s %s ", question, msg(messageConfirmOptions))
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return errAborted
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	for _, accepted := range strings.Split(msg(messageConfirmAccept), ",") {
+		if answer == accepted {
+			return nil
+		}
+	}
+
+	return errAborted
+}
+
+// describeStatus renders the staged changes of a worktree status, one file per
+// line prefixed with its status code.
+func describeStatus(status git.Status) string {
+	paths := make([]string, 0, len(status))
+	for path, fileStatus := range status {
+		if fileStatus.Staging != git.Unmodified {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var builder strings.Builder
+	for _, path := range paths {
+		code := byte(status[path].Staging)
+		fmt.Fprintf(&builder, "  %s  %s\n", colorize(statusColors[code], string(code)), path)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros are the shorthands accepted in place of the five schedule fields.
+var cronMacros = map[string]string{
+	"@yearly":  "0 0 1 1 *",
+	"@monthly": "0 0 1 * *",
+	"@weekly":  "0 0 * * 0",
+	"@daily":   "0 0 * * *",
+	"@hourly":  "0 * * * *",
+}
+
+// cronSchedule is a parsed five field cron expression: minute, hour, day of
+// month, month and day of week, each as a set of matching values.
+type cronSchedule struct {
+	minute, hour, day, month, weekday uint64
+	anyDay, anyWeekday                bool
+}
+
+// parseCron parses a cron expression with lists, ranges and steps, or one of
+// the cronMacros.
+func parseCron(expression string) (cronSchedule, error) {
+	var schedule cronSchedule
+
+	if macro, ok := cronMacros[expression]; ok {
+		expression = macro
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return schedule, fmt.Errorf("schedule '%s' must have five fields", expression)
+	}
+
+	bounds := []struct {
+		set      *uint64
+		min, max int
+	}{
+		{&schedule.minute, 0, 59},
+		{&schedule.hour, 0, 23},
+		{&schedule.day, 1, 31},
+		{&schedule.month, 1, 12},
+		{&schedule.weekday, 0, 7},
+	}
+
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i].min, bounds[i].max)
+		if err != nil {
+			return schedule, fmt.Errorf("schedule '%s': %w", expression, err)
+		}
+		*bounds[i].set = set
+	}
+
+	// Sunday may be written as both 0 and 7.
+	if schedule.weekday&(1<<7) != 0 {
+		schedule.weekday |= 1
+	}
+
+	schedule.anyDay = fields[2] == "*"
+	schedule.anyWeekday = fields[4] == "*"
+	return schedule, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps
+// into the set of values it matches.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		expression, stepText, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in '%s'", part)
+			}
+		}
+
+		start, end := min, max
+		if expression != "*" {
+			startText, endText, isRange := strings.Cut(expression, "-")
+
+			var err error
+			if start, err = strconv.Atoi(startText); err != nil {
+				return 0, fmt.Errorf("invalid value in '%s'", part)
+			}
+
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(endText); err != nil {
+					return 0, fmt.Errorf("invalid range in '%s'", part)
+				}
+			} else if hasStep {
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("'%s' is outside of %d-%d", part, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			set |= 1 << value
+		}
+	}
+
+	return set, nil
+}
+
+// matches reports whether the schedule fires at the minute of t.
+func (s cronSchedule) matches(t time.Time) bool {
+	if s.minute&(1<<t.Minute()) == 0 || s.hour&(1<<t.Hour()) == 0 || s.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+
+	day := s.day&(1<<t.Day()) != 0
+	weekday := s.weekday&(1<<int(t.Weekday())) != 0
+
+	// As in cron, a restricted day of month and day of week match either.
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// next returns the first time after t at which the schedule fires, searching
+// up to five years ahead.
+func (s cronSchedule) next(t time.Time) (time.Time, bool) {
+	candidate := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for candidate.Before(limit) {
+		if s.matches(candidate) {
+			return candidate, true
+		}
+		candidate = candidate.Add(time.Minute)
+	}
+
+	return time.Time{}, false
+}
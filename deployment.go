@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// deploy records the publish as a GitHub deployment to the configured
+// environment of the workflow repository: the deployment is created before
+// publish runs and marked successful or failed with its result.
+func deploy(cfg Config, repository string, publish func() error) error {
+	source, ref := os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_SHA")
+	if source == "" || ref == "" {
+		return fmt.Errorf("GITHUB_REPOSITORY and GITHUB_SHA environment variables must be set to create a deployment")
+	}
+
+	var deployment struct {
+		ID int64 `json:"id"`
+	}
+	err := githubRequest(cfg.GithubToken, "POST", fmt.Sprintf("/repos/%s/deployments", source), map[string]any{
+		"ref":               ref,
+		"environment":       cfg.DeployEnvironment,
+		"description":       fmt.Sprintf("Publish to %s", target{Repository: repository, Branch: cfg.Branch}),
+		"auto_merge":        false,
+		"required_contexts": []string{},
+	}, &deployment)
+	if err != nil {
+		return fmt.Errorf("failed to create deployment: %w", err)
+	}
+
+	if err := setDeploymentStatus(cfg, source, deployment.ID, "in_progress", ""); err != nil {
+		return err
+	}
+
+	if err := publish(); err != nil {
+		if statusErr := setDeploymentStatus(cfg, source, deployment.ID, "failure", ""); statusErr != nil {
+			fmt.Fprintln(os.Stderr, msg(messageDeploymentStatusFailed, statusErr))
+		}
+		return err
+	}
+
+	environmentURL := expandTemplate(strings.NewReplacer("{branch}", cfg.Branch, "{repository}", repository).Replace(cfg.DeploymentURL), time.Now())
+	if err := setDeploymentStatus(cfg, source, deployment.ID, "success", environmentURL); err != nil {
+		return err
+	}
+
+	return setOutput("deployment_id", fmt.Sprint(deployment.ID))
+}
+
+// setDeploymentStatus sets the state of a deployment, linking it to the
+// workflow run and, when given, to the deployed environment.
+func setDeploymentStatus(cfg Config, source string, id int64, state, environmentURL string) error {
+	body := map[string]any{"state": state}
+	if runID := os.Getenv("GITHUB_RUN_ID"); runID != "" {
+		body["log_url"] = fmt.Sprintf("%s/%s/actions/runs/%s", githubServerURL(), source, runID)
+	}
+	if environmentURL != "" {
+		body["environment_url"] = environmentURL
+	}
+
+	if err := githubRequest(cfg.GithubToken, "POST", fmt.Sprintf("/repos/%s/deployments/%d/statuses", source, id), body, nil); err != nil {
+		return fmt.Errorf("failed to set deployment status: %w", err)
+	}
+
+	return nil
+}
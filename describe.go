@@ -0,0 +1,111 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed action.yaml
+var actionMetadata []byte
+
+// modes lists the modes the action can run in.
+var modes = []string{modePublish, modeDrift, modeRepair, modeCheck, modeVerify, modeServer, modeOperator, modeRelease, modeCleanup, modePrune, modeHelm}
+
+// inputSchema is the JSON schema of a single input.
+type inputSchema struct {
+	Type        string   `json:"type"`
+	Description string   `json:"description,omitempty"`
+	Default     any      `json:"default,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Environment string   `json:"x-environment-variable"`
+}
+
+// describeInputs writes a JSON schema of all supported inputs, generated from
+// the Config structure and described by the action metadata.
+func describeInputs(w io.Writer) error {
+	properties, required, err := inputSchemas()
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "publish-directory inputs",
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	})
+}
+
+// inputSchemas returns the schemas of all supported inputs by their lower case
+// name, along with the names of the required inputs.
+func inputSchemas() (map[string]inputSchema, []string, error) {
+	var metadata struct {
+		Inputs map[string]struct {
+			Description string `yaml:"description"`
+			Required    bool   `yaml:"required"`
+		} `yaml:"inputs"`
+	}
+	if err := yaml.Unmarshal(actionMetadata, &metadata); err != nil {
+		return nil, nil, err
+	}
+
+	properties := map[string]inputSchema{}
+	required := []string{}
+
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+
+		variable := field.Tag.Get("env")
+		input, found := strings.CutPrefix(variable, "INPUT_")
+		if !found {
+			continue
+		}
+
+		schema := inputSchema{
+			Description: metadata.Inputs[input].Description,
+			Environment: variable,
+		}
+
+		value, hasDefault := field.Tag.Lookup("envDefault")
+		switch field.Type.Kind() {
+		case reflect.Bool:
+			schema.Type = "boolean"
+			if hasDefault {
+				schema.Default, _ = strconv.ParseBool(value)
+			}
+		case reflect.Int:
+			schema.Type = "integer"
+			if hasDefault {
+				schema.Default, _ = strconv.Atoi(value)
+			}
+		default:
+			schema.Type = "string"
+			if hasDefault {
+				schema.Default = value
+			}
+		}
+
+		if variable == "INPUT_MODE" {
+			schema.Enum = modes
+		}
+
+		name := strings.ToLower(input)
+		properties[name] = schema
+		if metadata.Inputs[input].Required {
+			required = append(required, name)
+		}
+	}
+
+	return properties, required, nil
+}
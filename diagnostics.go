@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/utils/trace"
+)
+
+// sensitiveNames are substrings of configuration and environment variable
+// names whose values must never end up in a diagnostics bundle.
+var sensitiveNames = []string{"TOKEN", "KEY", "PASSWORD", "PASSPHRASE", "SECRET"}
+
+// userinfoPattern matches the credentials of URLs in free-form text, which
+// errors may show with the password already masked.
+var userinfoPattern = regexp.MustCompile(`(://)[^/@\s"']+@`)
+
+// phaseTiming records how long a single phase of the publish took.
+type phaseTiming struct {
+	Name     string        `json:"name"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+}
+
+// diagnostics collects phase timings and, when enabled, the go-git trace of a
+// publish so they can be written out when it fails.
+type diagnostics struct {
+	phases []phaseTiming
+	trace  *bytes.Buffer
+}
+
+// newDiagnostics creates a diagnostics collector. When tracing is set the git
+// operations are traced into memory.
+func newDiagnostics(tracing bool) *diagnostics {
+	d := &diagnostics{}
+
+	if tracing {
+		d.trace = &bytes.Buffer{}
+		trace.SetLogger(log.New(d.trace, "", log.Ltime|log.Lmicroseconds))
+		trace.SetTarget(trace.General | trace.Packet)
+	}
+
+	return d
+}
+
+// phase marks the end of the current phase and the start of the next one.
+func (d *diagnostics) phase(name string) {
+	if d == nil {
+		return
+	}
+
+	now := time.Now()
+	if len(d.phases) > 0 {
+		last := &d.phases[len(d.phases)-1]
+		last.Duration = now.Sub(last.Started)
+	}
+
+	if name != "" {
+		d.phases = append(d.phases, phaseTiming{Name: name, Started: now})
+	}
+}
+
+// write stores a redacted diagnostics bundle for the failed publish in dir.
+func (d *diagnostics) write(dir string, cfg Config, publishErr error) error {
+	d.phase("")
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	secrets := secretValues(cfg)
+
+	files := map[string]any{
+		"config.json":      redactConfig(cfg, secrets),
+		"timings.json":     d.phases,
+		"environment.json": redactedEnvironment(secrets),
+	}
+
+	for name, content := range files {
+		data, err := json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, name), append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "error.txt"), []byte(redact(publishErr.Error(), secrets)+"\n"), 0o644); err != nil {
+		return err
+	}
+
+	if d.trace != nil {
+		if err := os.WriteFile(filepath.Join(dir, "git-trace.log"), []byte(redact(d.trace.String(), secrets)), 0o644); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, msg(messageDiagnosticsWritten, dir))
+	return nil
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToUpper(name)
+	for _, sensitive := range sensitiveNames {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretValues returns the non-empty values of all sensitive configuration
+// fields, and the credentials embedded in URLs of the others, so they can be
+// scrubbed from free-form text wherever they appear.
+func secretValues(cfg Config) []string {
+	var secrets []string
+
+	value := reflect.ValueOf(cfg)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Type.Kind() != reflect.String {
+			continue
+		}
+
+		text := value.Field(i).String()
+		if isSensitive(field.Name) {
+			if text != "" {
+				secrets = append(secrets, text)
+			}
+			continue
+		}
+
+		if parsed, err := url.Parse(text); err == nil && parsed.User != nil {
+			secrets = append(secrets, parsed.User.String())
+			if password, ok := parsed.User.Password(); ok && password != "" {
+				secrets = append(secrets, password)
+			}
+		}
+	}
+
+	return secrets
+}
+
+// stripUserinfo removes the credentials from a URL, leaving other text as is.
+func stripUserinfo(text string) string {
+	if !strings.Contains(text, "://") {
+		return text
+	}
+
+	parsed, err := url.Parse(text)
+	if err != nil || parsed.User == nil {
+		return text
+	}
+
+	parsed.User = nil
+	return parsed.String()
+}
+
+func redact(text string, secrets []string) string {
+	// Longer secrets go first, so a secret containing another one is still
+	// replaced as a whole.
+	secrets = slices.Clone(secrets)
+	slices.SortFunc(secrets, func(a, b string) int { return len(b) - len(a) })
+
+	for _, secret := range secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, "***")
+		}
+	}
+	return userinfoPattern.ReplaceAllString(text, "$1")
+}
+
+// redactConfig returns a snapshot of the configuration keyed by environment
+// variable, with sensitive values masked and the other values scrubbed of
+// credentials.
+func redactConfig(cfg Config, secrets []string) map[string]any {
+	snapshot := map[string]any{}
+
+	value := reflect.ValueOf(cfg)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+
+		name := field.Tag.Get("env")
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldValue := value.Field(i).Interface()
+		switch {
+		case isSensitive(field.Name) && !value.Field(i).IsZero():
+			fieldValue = "***"
+		case field.Type.Kind() == reflect.String:
+			fieldValue = redact(stripUserinfo(value.Field(i).String()), secrets)
+		}
+
+		snapshot[name] = fieldValue
+	}
+
+	return snapshot
+}
+
+// redactedEnvironment returns the GitHub, runner and input environment
+// variables, including inputs under the configured prefix, with sensitive
+// values masked and the other values scrubbed of credentials.
+func redactedEnvironment(secrets []string) map[string]string {
+	environment := map[string]string{}
+
+	prefixes := []string{"GITHUB_", "RUNNER_", "INPUT_"}
+	if prefix := os.Getenv(envPrefixVariable); prefix != "" {
+		prefixes = append(prefixes, prefix)
+	}
+
+	keys := os.Environ()
+	sort.Strings(keys)
+
+	for _, entry := range keys {
+		name, value, _ := strings.Cut(entry, "=")
+		if !slices.ContainsFunc(prefixes, func(prefix string) bool { return strings.HasPrefix(name, prefix) }) {
+			continue
+		}
+
+		if isSensitive(name) && value != "" {
+			value = "***"
+		}
+		value = redact(stripUserinfo(value), secrets)
+
+		environment[name] = value
+	}
+
+	return environment
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// publishTrailer marks commits created by the action, so commits made by
+// anyone else can be told apart.
+const publishTrailer = "Published-By: kontrolplane/publish-directory"
+
+// appendTrailers adds git trailers to the end of a commit message, separated
+// from the body by a blank line unless the message already ends in trailers.
+func appendTrailers(message string, trailers ...string) string {
+	message = strings.TrimRight(message, "\n")
+	if len(trailers) == 0 {
+		return message + "\n"
+	}
+
+	lines := strings.Split(message, "\n")
+	last := lines[len(lines)-1]
+	if len(lines) == 1 || !isTrailer(last) {
+		message += "\n"
+	}
+
+	return message + "\n" + strings.Join(trailers, "\n") + "\n"
+}
+
+func isTrailer(line string) bool {
+	key, value, found := strings.Cut(line, ": ")
+	return found && value != "" && key != "" && !strings.Contains(key, " ")
+}
+
+// hasTrailer reports whether the commit message carries the trailer.
+func hasTrailer(message, trailer string) bool {
+	for _, line := range strings.Split(message, "\n") {
+		if strings.TrimSpace(line) == trailer {
+			return true
+		}
+	}
+	return false
+}
+
+// driftReport describes the commits on a branch made after the last publish.
+type driftReport struct {
+	Tip           plumbing.Hash
+	LastPublished plumbing.Hash
+	Foreign       []*object.Commit
+}
+
+func (r driftReport) drifted() bool {
+	return len(r.Foreign) > 0
+}
+
+// cloneBranch clones the full history of a single branch into dir.
+func cloneBranch(gitURL, branch, dir string, auth transport.AuthMethod) (*git.Repository, error) {
+	return git.PlainClone(dir, false, &git.CloneOptions{
+		URL:           gitURL,
+		Auth:          auth,
+		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		SingleBranch:  true,
+	})
+}
+
+// detectDrift walks the branch history from its tip back to the last commit
+// created by the action, collecting every commit made by someone else.
+func detectDrift(repo *git.Repository) (driftReport, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return driftReport{}, fmt.Errorf("failed to resolve branch tip: %w", err)
+	}
+
+	report := driftReport{Tip: head.Hash()}
+
+	commits, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return report, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer commits.Close()
+
+	err = commits.ForEach(func(commit *object.Commit) error {
+		if hasTrailer(commit.Message, publishTrailer) {
+			report.LastPublished = commit.Hash
+			return storer.ErrStop
+		}
+
+		report.Foreign = append(report.Foreign, commit)
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	if report.LastPublished.IsZero() {
+		return report, errors.New("no commit created by publish-directory found on the branch")
+	}
+
+	return report, nil
+}
+
+// checkDrift reports whether the target branch has been modified by anyone
+// other than the action since its last publish.
+func checkDrift(cfg Config) error {
+	_, url, auth, err := resolveRemote(cfg)
+	if err != nil {
+		return err
+	}
+
+	temporaryDirectory, err := os.MkdirTemp(temporaryRoot(), "kontrolplane-publish-directory-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(temporaryDirectory)
+
+	repo, err := cloneBranch(url, cfg.Branch, temporaryDirectory, auth)
+	if err != nil {
+		return fmt.Errorf("failed to clone branch '%s': %w", cfg.Branch, err)
+	}
+
+	report, err := detectDrift(repo)
+	if err != nil {
+		return err
+	}
+
+	foreign := make([]string, 0, len(report.Foreign))
+	for _, commit := range report.Foreign {
+		foreign = append(foreign, commit.Hash.String())
+	}
+
+	outputs := map[string]string{
+		"drifted":         fmt.Sprintf("%t", report.drifted()),
+		"last_published":  report.LastPublished.String(),
+		"foreign_commits": strings.Join(foreign, "\n"),
+	}
+	for name, value := range outputs {
+		if err := setOutput(name, value); err != nil {
+			return fmt.Errorf("failed to set output %s: %w", name, err)
+		}
+	}
+
+	if !report.drifted() {
+		fmt.Println(msg(messageNoDrift, cfg.Branch, report.LastPublished))
+		return nil
+	}
+
+	fmt.Printf("::warning title=%s::%s\n", msg(messageDriftTitle), msg(messageDrift, cfg.Branch, len(report.Foreign), report.LastPublished))
+	for _, commit := range report.Foreign {
+		fmt.Printf("  %s %s <%s> %s\n", commit.Hash.String()[:7], commit.Author.Name, commit.Author.Email, strings.SplitN(commit.Message, "\n", 2)[0])
+	}
+
+	if cfg.Mode == modeRepair {
+		return repairDrift(repo, cfg, report, auth)
+	}
+
+	return nil
+}
+
+// repairDrift resets the branch to the last published commit, optionally
+// keeping the foreign commits reachable from a backup branch.
+func repairDrift(repo *git.Repository, cfg Config, report driftReport, auth transport.AuthMethod) error {
+	if report.LastPublished.IsZero() {
+		return fmt.Errorf("branch '%s' has no earlier publish to reset to", cfg.Branch)
+	}
+
+	if err := confirm(cfg, msg(messageConfirmRepair, cfg.Branch, report.LastPublished, len(report.Foreign))); err != nil {
+		return err
+	}
+
+	refSpecs := []config.RefSpec{
+		config.RefSpec(fmt.Sprintf("+%s:%s", report.LastPublished, plumbing.NewBranchReferenceName(cfg.Branch))),
+	}
+
+	if cfg.DriftBackup != "" {
+		refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("+%s:%s", report.Tip, plumbing.NewBranchReferenceName(cfg.DriftBackup))))
+	}
+
+	if err := repo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   refSpecs,
+		Auth:       auth,
+		Progress:   progressWriter(),
+	}); err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to repair branch: %w", err)
+	}
+
+	if cfg.DriftBackup != "" {
+		fmt.Println(msg(messageDriftBackup, cfg.DriftBackup))
+	}
+
+	fmt.Println(msg(messageDriftRepaired, cfg.Branch, report.LastPublished))
+	return setOutput("repaired", "true")
+}
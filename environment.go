@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// envPrefixVariable configures an alternative prefix for the inputs, for
+	// CI systems that do not follow the GitHub Actions INPUT_ convention.
+	envPrefixVariable = "PUBLISH_DIRECTORY_ENV_PREFIX"
+	// envAliasesVariable configures alternative variable names for inputs as
+	// newline or comma separated 'INPUT_NAME=ALTERNATIVE_NAME' pairs.
+	envAliasesVariable = "PUBLISH_DIRECTORY_ENV_ALIASES"
+)
+
+// configEnvironment returns the environment the configuration is parsed from.
+// Inputs that are not set under their INPUT_ name are filled in from their
+// aliases or from the configured prefix, in that order, and then from the
+// config file.
+func configEnvironment() (map[string]string, error) {
+	environment := map[string]string{}
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		environment[name] = value
+	}
+
+	aliases := map[string][]string{}
+	for _, pair := range splitList(environment[envAliasesVariable]) {
+		name, alias, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid alias '%s', expected INPUT_NAME=ALTERNATIVE_NAME", pair)
+		}
+		name = strings.TrimSpace(name)
+		aliases[name] = append(aliases[name], strings.TrimSpace(alias))
+	}
+
+	prefix := environment[envPrefixVariable]
+
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		name := configType.Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+
+		if _, ok := environment[name]; ok {
+			continue
+		}
+
+		candidates := aliases[name]
+		if input, found := strings.CutPrefix(name, "INPUT_"); found && prefix != "" {
+			candidates = append(candidates, prefix+input)
+		}
+
+		for _, candidate := range candidates {
+			if value, ok := environment[candidate]; ok {
+				environment[name] = value
+				break
+			}
+		}
+	}
+
+	if err := applyConfigFile(environment); err != nil {
+		return nil, err
+	}
+
+	return environment, nil
+}
+
+// unknownInputs returns the INPUT_ variables in the environment that do not
+// correspond to any input, which usually are misspelled input names.
+func unknownInputs(environment map[string]string) []string {
+	known := map[string]bool{}
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		if name := configType.Field(i).Tag.Get("env"); name != "" {
+			known[name] = true
+		}
+	}
+
+	var unknown []string
+	for name := range environment {
+		if strings.HasPrefix(name, "INPUT_") && !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	sort.Strings(unknown)
+	return unknown
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// pathFilter reports whether a path, relative to the source folder and split
+// into its components, should be left out of the publish.
+type pathFilter func(path []string, isDir bool) bool
+
+// splitPath splits a relative, OS specific path into its components.
+func splitPath(path string) []string {
+	if path == "" || path == "." {
+		return nil
+	}
+	return strings.Split(filepath.ToSlash(path), "/")
+}
+
+// findRepositoryRoot walks up from dir until it finds the directory containing
+// the .git folder of the enclosing repository.
+func findRepositoryRoot(dir string) (string, error) {
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current, nil
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", fmt.Errorf("'%s' is not inside a git repository", dir)
+		}
+		current = parent
+	}
+}
+
+// exportIgnoreFilter builds a filter from the export-ignore attributes of the
+// repository containing folder, matching the behaviour of git archive.
+func exportIgnoreFilter(folder string) (pathFilter, error) {
+	root, err := findRepositoryRoot(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	absoluteFolder, err := filepath.Abs(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	relativeFolder, err := filepath.Rel(root, absoluteFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := splitPath(relativeFolder)
+	fs := osfs.New(root)
+
+	var attributes []gitattributes.MatchAttribute
+	for i := 0; i < len(prefix); i++ {
+		parent := append([]string{}, prefix[:i]...)
+		parentAttributes, err := gitattributes.ReadAttributesFile(fs, parent, ".gitattributes", i == 0)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, parentAttributes...)
+	}
+
+	folderAttributes, err := gitattributes.ReadPatterns(fs, append([]string{}, prefix...))
+	if err != nil {
+		return nil, err
+	}
+	attributes = append(attributes, folderAttributes...)
+
+	matcher := gitattributes.NewMatcher(attributes)
+
+	return func(path []string, isDir bool) bool {
+		fullPath := append(append([]string{}, prefix...), path...)
+		results, matched := matcher.Match(fullPath, []string{"export-ignore"})
+		if !matched {
+			return false
+		}
+
+		attribute, ok := results["export-ignore"]
+		return ok && attribute.IsSet()
+	}, nil
+}
+
+// gitignoreFilter excludes the paths ignored by the .gitignore files of the
+// repository containing folder. Patterns ignoring the folder itself, as build
+// output usually is, are left out so that they do not exclude everything.
+func gitignoreFilter(folder string) (pathFilter, error) {
+	root, err := findRepositoryRoot(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	absoluteFolder, err := filepath.Abs(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	relativeFolder, err := filepath.Rel(root, absoluteFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := splitPath(relativeFolder)
+
+	patterns, err := gitignore.ReadPatterns(osfs.New(root), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var applicable []gitignore.Pattern
+	for _, pattern := range patterns {
+		ignoresFolder := false
+		for i := 1; i <= len(prefix); i++ {
+			if pattern.Match(prefix[:i], true) == gitignore.Exclude {
+				ignoresFolder = true
+				break
+			}
+		}
+
+		if !ignoresFolder {
+			applicable = append(applicable, pattern)
+		}
+	}
+
+	matcher := gitignore.NewMatcher(applicable)
+	return func(path []string, isDir bool) bool {
+		return matcher.Match(append(append([]string{}, prefix...), path...), isDir)
+	}, nil
+}
+
+// publishIgnoreFile lists, in the .gitignore syntax, the files of the source
+// folder that are not published.
+const publishIgnoreFile = ".publishignore"
+
+// publishIgnoreFilter excludes the paths matching the patterns of the
+// .publishignore file at the root of folder, and the file itself. It returns
+// nil when the folder has no such file.
+func publishIgnoreFilter(folder string) (pathFilter, error) {
+	data, err := os.ReadFile(filepath.Join(folder, publishIgnoreFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []gitignore.Pattern
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+
+	matcher := gitignore.NewMatcher(patterns)
+	return func(path []string, isDir bool) bool {
+		if len(path) == 1 && path[0] == publishIgnoreFile {
+			return true
+		}
+		return matcher.Match(path, isDir)
+	}, nil
+}
+
+// excludeFilter excludes paths matching any of the glob patterns, which follow
+// the .gitignore syntax: patterns without a slash match at any depth and '**'
+// matches any number of directories.
+func excludeFilter(patterns []string) pathFilter {
+	parsed := make([]gitignore.Pattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		parsed = append(parsed, gitignore.ParsePattern(pattern, nil))
+	}
+
+	matcher := gitignore.NewMatcher(parsed)
+	return func(path []string, isDir bool) bool {
+		return matcher.Match(path, isDir)
+	}
+}
+
+// keepFilter excludes the files matching the keep patterns that already exist
+// in destination, so that they are never overwritten by the source.
+func keepFilter(patterns []string, destination string) pathFilter {
+	removed := includeFilter(patterns)
+	return func(path []string, isDir bool) bool {
+		if isDir || removed(path, false) {
+			return false
+		}
+
+		_, err := os.Lstat(filepath.Join(destination, filepath.Join(path...)))
+		return err == nil
+	}
+}
+
+// includeFilter excludes every path not matching one of the glob patterns,
+// which follow the .gitignore syntax. Files inside a matching directory are
+// included, and directories that cannot contain matches are pruned.
+func includeFilter(patterns []string) pathFilter {
+	parsed := make([]gitignore.Pattern, 0, len(patterns))
+	var anchored [][]string
+	unanchored := false
+
+	for _, pattern := range patterns {
+		parsed = append(parsed, gitignore.ParsePattern(pattern, nil))
+
+		trimmed := strings.Trim(pattern, "/")
+		if !strings.Contains(trimmed, "/") {
+			unanchored = true
+			continue
+		}
+		anchored = append(anchored, strings.Split(trimmed, "/"))
+	}
+
+	matcher := gitignore.NewMatcher(parsed)
+
+	matched := func(path []string, isDir bool) bool {
+		for i := 1; i <= len(path); i++ {
+			if matcher.Match(path[:i], i < len(path) || isDir) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// mayContainMatches reports whether a pattern can match below dir.
+	mayContainMatches := func(dir []string) bool {
+		if unanchored {
+			return true
+		}
+
+		for _, components := range anchored {
+			possible := true
+			for i, name := range dir {
+				if i >= len(components) || components[i] == "**" {
+					break
+				}
+
+				if match, _ := filepath.Match(components[i], name); !match {
+					possible = false
+					break
+				}
+			}
+
+			if possible {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(path []string, isDir bool) bool {
+		if matched(path, isDir) {
+			return false
+		}
+		return !isDir || !mayContainMatches(path)
+	}
+}
+
+// sourceFilters returns the filters selecting which files of the source folder
+// are published.
+func sourceFilters(cfg Config) ([]pathFilter, error) {
+	var filters []pathFilter
+	if cfg.SafetyExcludes {
+		filters = append(filters, safetyExcludeFilter())
+	}
+
+	if cfg.Include != "" {
+		filters = append(filters, includeFilter(splitList(cfg.Include)))
+	}
+
+	if cfg.Exclude != "" {
+		filters = append(filters, excludeFilter(splitList(cfg.Exclude)))
+	}
+
+	filter, err := publishIgnoreFilter(cfg.Folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", publishIgnoreFile, err)
+	}
+	if filter != nil {
+		filters = append(filters, filter)
+	}
+
+	if cfg.RespectGitignore {
+		filter, err := gitignoreFilter(cfg.Folder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load .gitignore patterns: %w", err)
+		}
+		filters = append(filters, filter)
+	}
+
+	if cfg.ExportIgnore {
+		filter, err := exportIgnoreFilter(cfg.Folder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load export-ignore attributes: %w", err)
+		}
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+// isFiltered reports whether any of the filters excludes the path.
+func isFiltered(filters []pathFilter, path []string, isDir bool) bool {
+	for _, filter := range filters {
+		if filter(path, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// safetyExcludedDirectories and safetyExcludedFiles are dependency, cache and
+// build artifacts that are almost never meant to be published.
+var (
+	safetyExcludedDirectories = []string{"node_modules", ".cache", "__pycache__", ".terraform"}
+	safetyExcludedFiles       = []string{".DS_Store"}
+)
+
+// safetyExcludeFilter excludes the built-in set of dependency and build
+// directories, announcing every excluded path.
+func safetyExcludeFilter() pathFilter {
+	return func(path []string, isDir bool) bool {
+		if len(path) == 0 {
+			return false
+		}
+
+		excluded := false
+		for _, component := range path {
+			for _, directory := range safetyExcludedDirectories {
+				if component == directory {
+					excluded = true
+				}
+			}
+		}
+
+		for _, file := range safetyExcludedFiles {
+			if !isDir && path[len(path)-1] == file {
+				excluded = true
+			}
+		}
+
+		if excluded {
+			fmt.Println(msg(messageSafetyExclude, strings.Join(path, "/")))
+		}
+		return excluded
+	}
+}
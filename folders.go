@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	folderConflictOverwrite = "overwrite"
+	folderConflictSkip      = "skip"
+	folderConflictError     = "error"
+)
+
+// sourceFolder is a folder that is published, at Target within the published
+// tree.
+type sourceFolder struct {
+	Path   string
+	Target string
+}
+
+// sourceFolders returns the folders that are layered into the published tree,
+// in the order in which they are applied. Each folder is either a path or a
+// 'path=>target' mapping.
+func sourceFolders(cfg Config) []sourceFolder {
+	var folders []sourceFolder
+	for _, item := range splitList(cfg.Folder) {
+		path, target, _ := strings.Cut(item, "=>")
+		folders = append(folders, sourceFolder{
+			Path:   strings.TrimSpace(path),
+			Target: strings.Trim(filepath.ToSlash(strings.TrimSpace(target)), "/"),
+		})
+	}
+	return folders
+}
+
+// folderFiles returns the paths, relative to folder, of the files in folder
+// that pass the filters.
+func folderFiles(folder string, filters []pathFilter) ([]string, error) {
+	var files []string
+
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		relativePath, err := filepath.Rel(folder, path)
+		if err != nil {
+			return err
+		}
+
+		if relativePath != "." && isFiltered(filters, splitPath(relativePath), info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.IsDir() {
+			files = append(files, relativePath)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// sourceFiles returns the files to publish, keyed by their slash separated
+// path in the published tree, with the path of the local file they are copied
+// from. Files of later folders replace, are shadowed by or conflict with the
+// files of earlier folders at the same path depending on the folder conflict
+// policy.
+func sourceFiles(cfg Config) (map[string]string, error) {
+	files := map[string]string{}
+
+	for _, folder := range sourceFolders(cfg) {
+		folderConfig := cfg
+		folderConfig.Folder = folder.Path
+
+		filters, err := sourceFilters(folderConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		paths, err := folderFiles(folder.Path, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read folder '%s': %w", folder.Path, err)
+		}
+
+		for _, path := range paths {
+			name := filepath.ToSlash(path)
+			if folder.Target != "" {
+				name = folder.Target + "/" + name
+			}
+
+			if previous, ok := files[name]; ok {
+				switch cfg.FolderConflict {
+				case folderConflictSkip:
+					continue
+				case folderConflictError:
+					return nil, fmt.Errorf("'%s' exists in both '%s' and '%s'", name, previous, filepath.Join(folder.Path, path))
+				}
+			}
+
+			files[name] = filepath.Join(folder.Path, path)
+		}
+	}
+
+	return files, nil
+}
+
+// copyFolders copies the source folders into destination. A single unmapped
+// folder is copied as is, several folders are layered on top of each other.
+func copyFolders(cfg Config, destination string) error {
+	if folders := sourceFolders(cfg); len(folders) == 1 && folders[0].Target == "" {
+		folderConfig := cfg
+		folderConfig.Folder = folders[0].Path
+
+		filters, err := sourceFilters(folderConfig)
+		if err != nil {
+			return err
+		}
+
+		if cfg.KeepFiles != "" {
+			filters = append(filters, keepFilter(splitList(cfg.KeepFiles), destination))
+		}
+
+		return copyDirectory(folders[0].Path, destination, filters)
+	}
+
+	files, err := sourceFiles(cfg)
+	if err != nil {
+		return err
+	}
+
+	var kept pathFilter
+	if cfg.KeepFiles != "" {
+		kept = keepFilter(splitList(cfg.KeepFiles), destination)
+	}
+
+	for name, source := range files {
+		if kept != nil && kept(splitPath(name), false) {
+			continue
+		}
+
+		target := filepath.Join(destination, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+
+		if err := copyFile(source, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
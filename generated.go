@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// generatedPaths returns the slash separated paths of the files the action
+// generates itself, which change on every run and therefore do not count as
+// changes on their own.
+func generatedPaths(cfg Config) []string {
+	var paths []string
+
+	if cfg.SBOM != "" {
+		paths = append(paths, sbomPath(cfg))
+	}
+
+	if cfg.BadgePath != "" {
+		paths = append(paths, filepath.ToSlash(cfg.BadgePath))
+	}
+
+	return paths
+}
+
+// hasChanges reports whether the status contains changes to files other than
+// the ignored ones.
+func hasChanges(status git.Status, ignored []string) bool {
+	for path, fileStatus := range status {
+		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified {
+			continue
+		}
+
+		generated := false
+		for _, ignore := range ignored {
+			if path == ignore {
+				generated = true
+				break
+			}
+		}
+
+		if !generated {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// giteaProvider publishes to repositories on self-hosted Gitea and Forgejo
+// instances, addressed as owner/name.
+type giteaProvider struct{}
+
+func (giteaProvider) remote(cfg Config, repository string) (string, transport.AuthMethod, error) {
+	if cfg.GiteaURL == "" && !strings.Contains(repository, "://") {
+		return "", nil, fmt.Errorf("gitea_url is required to publish to %s on Gitea", repository)
+	}
+
+	// Without a user name Gitea takes the user name as the token when the
+	// password is x-oauth-basic.
+	auth := &http.BasicAuth{
+		Username: cfg.GiteaToken,
+		Password: "x-oauth-basic",
+	}
+	if cfg.GiteaUsername != "" {
+		auth = &http.BasicAuth{
+			Username: cfg.GiteaUsername,
+			Password: cfg.GiteaToken,
+		}
+	}
+
+	return providerURL(strings.TrimSuffix(cfg.GiteaURL, "/"), repository), auth, nil
+}
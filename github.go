@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// githubAPIError is returned for GitHub API responses with an error status.
+type githubAPIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *githubAPIError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// githubAPIURL returns the base URL of the GitHub REST API.
+func githubAPIURL() string {
+	if url := os.Getenv("GITHUB_API_URL"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return "https://api.github.com"
+}
+
+// githubServerURL returns the base URL of the GitHub instance, which differs
+// from github.com on GitHub Enterprise Server.
+func githubServerURL() string {
+	if url := os.Getenv("GITHUB_SERVER_URL"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return "https://github.com"
+}
+
+// githubHost returns the host name of the GitHub instance.
+func githubHost() string {
+	parsed, err := url.Parse(githubServerURL())
+	if err != nil || parsed.Hostname() == "" {
+		return "github.com"
+	}
+	return parsed.Hostname()
+}
+
+// applyGithubURLs overrides the server and API URLs of the GitHub instance
+// with the configured ones. Without an API URL the API of a GitHub Enterprise
+// Server instance is assumed to be served below /api/v3.
+func applyGithubURLs(serverURL, apiURL string) error {
+	if serverURL != "" {
+		if err := os.Setenv("GITHUB_SERVER_URL", serverURL); err != nil {
+			return err
+		}
+
+		if apiURL == "" && githubHost() != "github.com" {
+			apiURL = githubServerURL() + "/api/v3"
+		}
+	}
+
+	if apiURL != "" {
+		return os.Setenv("GITHUB_API_URL", apiURL)
+	}
+	return nil
+}
+
+// githubProvider publishes to GitHub repositories, addressed as owner/name,
+// authenticating with the token or the SSH deploy key.
+type githubProvider struct{}
+
+func (githubProvider) remote(cfg Config, repository string) (string, transport.AuthMethod, error) {
+	if cfg.SSHKey != "" {
+		auth, err := sshAuth(cfg, githubHost())
+		if err != nil {
+			return "", nil, err
+		}
+
+		return fmt.Sprintf("git@%s:%s.git", githubHost(), repository), auth, nil
+	}
+
+	url := fmt.Sprintf("%s/%s.git", githubServerURL(), repository)
+
+	return url, &githttp.BasicAuth{
+		Username: "x-access-token",
+		Password: cfg.GithubToken,
+	}, nil
+}
+
+// githubRequest performs a GitHub REST API request authenticated with token.
+// The body, when not nil, is sent as JSON and the response is decoded into
+// result, when not nil.
+func githubRequest(token, method, path string, body, result any) error {
+	_, err := githubRequestURL(token, method, githubAPIURL()+path, body, result)
+	return err
+}
+
+// nextLinkPattern matches the link to the next page in a Link header.
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// githubList reads every page of a GitHub REST API listing at path, following
+// the next links of the Link header.
+func githubList[T any](token, path string) ([]T, error) {
+	var items []T
+
+	next := githubAPIURL() + path
+	for next != "" {
+		var page []T
+		header, err := githubRequestURL(token, "GET", next, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+
+		next = ""
+		if match := nextLinkPattern.FindStringSubmatch(header.Get("Link")); match != nil {
+			next = match[1]
+		}
+	}
+
+	return items, nil
+}
+
+// githubRequestURL performs a GitHub REST API request to the absolute URL like
+// githubRequest, and returns the headers of the response.
+func githubRequestURL(token, method, requestURL string, body, result any) (http.Header, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, requestURL, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		var failure struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&failure)
+
+		path := strings.TrimPrefix(requestURL, githubAPIURL())
+		return nil, &githubAPIError{Method: method, Path: path, StatusCode: response.StatusCode, Message: failure.Message}
+	}
+
+	if result == nil {
+		return response.Header, nil
+	}
+
+	return response.Header, json.NewDecoder(response.Body).Decode(result)
+}
+
+// githubEventNumber returns the number of the pull request or issue that
+// triggered the workflow, or zero when the event has none.
+func githubEventNumber() int {
+	path := os.Getenv("GITHUB_EVENT_PATH")
+	if path == "" {
+		return 0
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+
+	var event struct {
+		Number      int `json:"number"`
+		PullRequest struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+		Issue struct {
+			Number int `json:"number"`
+		} `json:"issue"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return 0
+	}
+
+	for _, number := range []int{event.PullRequest.Number, event.Issue.Number, event.Number} {
+		if number != 0 {
+			return number
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// gitlabProvider publishes to GitLab projects, addressed by their path such
+// as group/project.
+type gitlabProvider struct{}
+
+// gitlabServerURL returns the base URL of the GitLab instance, defaulting to
+// the instance a GitLab CI job runs on and otherwise gitlab.com.
+func gitlabServerURL(cfg Config) string {
+	for _, url := range []string{cfg.GitlabURL, os.Getenv("CI_SERVER_URL")} {
+		if url != "" {
+			return strings.TrimSuffix(url, "/")
+		}
+	}
+	return "https://gitlab.com"
+}
+
+func (gitlabProvider) remote(cfg Config, project string) (string, transport.AuthMethod, error) {
+	// GitLab accepts personal, project and group access tokens as the
+	// password of the oauth2 user.
+	return providerURL(gitlabServerURL(cfg), project), &http.BasicAuth{
+		Username: "oauth2",
+		Password: cfg.GitlabToken,
+	}, nil
+}
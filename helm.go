@@ -0,0 +1,217 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// helmIndexFile is the index of a Helm chart repository.
+const helmIndexFile = "index.yaml"
+
+// readHelmIndex returns the index.yaml of the branch in dir, or nil when the
+// branch has none. It is read before the source is copied, which could replace
+// or, when cleaning, remove it.
+func readHelmIndex(dir string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, helmIndexFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// mergeHelmIndex adds the packaged charts in dir that are missing from the
+// branch index, as read by readHelmIndex, and writes the result to the
+// index.yaml in dir, leaving the entries of charts that were published
+// before, including their digests and URLs, untouched. An index.yaml in the
+// source is replaced. The index is decoded generically so that fields written
+// by other tools, such as annotations and serverInfo, survive the merge. A
+// package whose version is indexed with a different digest fails the merge,
+// as a published version must not change.
+func mergeHelmIndex(cfg Config, dir string, branchIndex []byte) error {
+	indexPath := filepath.Join(dir, helmIndexFile)
+
+	index := map[string]any{}
+	if err := yaml.Unmarshal(branchIndex, &index); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", helmIndexFile, err)
+	}
+	if index == nil {
+		index = map[string]any{}
+	}
+
+	if index["apiVersion"] == nil {
+		index["apiVersion"] = "v1"
+	}
+
+	entries, ok := index["entries"].(map[string]any)
+	if !ok {
+		if index["entries"] != nil {
+			return fmt.Errorf("failed to parse %s: entries is not a mapping", helmIndexFile)
+		}
+		entries = map[string]any{}
+		index["entries"] = entries
+	}
+
+	packages, err := filepath.Glob(filepath.Join(dir, "*.tgz"))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	added := 0
+
+	for _, pkg := range packages {
+		metadata, err := readChartMetadata(pkg)
+		if err != nil {
+			return fmt.Errorf("failed to read chart '%s': %w", filepath.Base(pkg), err)
+		}
+
+		name, _ := metadata["name"].(string)
+		version, _ := metadata["version"].(string)
+		if name == "" || version == "" {
+			return fmt.Errorf("chart '%s' has no name or version", filepath.Base(pkg))
+		}
+
+		digest, err := fileDigest(pkg)
+		if err != nil {
+			return err
+		}
+
+		versions, _ := entries[name].([]any)
+		if existing := chartVersion(versions, version); existing != nil {
+			if indexed, _ := existing["digest"].(string); indexed != "" && indexed != digest {
+				return fmt.Errorf("chart %s %s is indexed with a different digest than '%s', publish changes under a new chart version", name, version, filepath.Base(pkg))
+			}
+			continue
+		}
+
+		chartURL := filepath.Base(pkg)
+		if cfg.HelmRepoURL != "" {
+			chartURL = strings.TrimSuffix(cfg.HelmRepoURL, "/") + "/" + path.Base(filepath.ToSlash(pkg))
+		}
+
+		metadata["urls"] = []string{chartURL}
+		metadata["digest"] = digest
+		metadata["created"] = now
+		if metadata["apiVersion"] == nil {
+			metadata["apiVersion"] = "v1"
+		}
+
+		fmt.Println(msg(messageAddingChart, name, version, helmIndexFile))
+		entries[name] = append(versions, metadata)
+		added++
+	}
+
+	// Without new charts the branch index is kept byte for byte.
+	if added == 0 {
+		if branchIndex == nil {
+			if err := os.Remove(indexPath); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}
+		return os.WriteFile(indexPath, branchIndex, 0o644)
+	}
+
+	for name := range entries {
+		versions, _ := entries[name].([]any)
+		sort.SliceStable(versions, func(i, j int) bool {
+			return compareVersions(entryVersion(versions[i]), entryVersion(versions[j])) > 0
+		})
+	}
+	index["generated"] = now
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(index); err != nil {
+		return err
+	}
+
+	return os.WriteFile(indexPath, buffer.Bytes(), 0o644)
+}
+
+// chartVersion returns the index entry of a chart for the version, or nil
+// when the version is not indexed.
+func chartVersion(versions []any, version string) map[string]any {
+	for _, entry := range versions {
+		if entry, ok := entry.(map[string]any); ok && entry["version"] == version {
+			return entry
+		}
+	}
+	return nil
+}
+
+// entryVersion returns the version of an index entry.
+func entryVersion(entry any) string {
+	if entry, ok := entry.(map[string]any); ok {
+		version, _ := entry["version"].(string)
+		return version
+	}
+	return ""
+}
+
+// readChartMetadata reads the Chart.yaml at the root of a packaged chart.
+func readChartMetadata(pkg string) (map[string]any, error) {
+	file, err := os.Open(pkg)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer compressed.Close()
+
+	archive := tar.NewReader(compressed)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no Chart.yaml found")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parts := strings.Split(strings.TrimPrefix(header.Name, "./"), "/")
+		if len(parts) != 2 || parts[1] != "Chart.yaml" {
+			continue
+		}
+
+		var metadata map[string]any
+		if err := yaml.NewDecoder(archive).Decode(&metadata); err != nil {
+			return nil, err
+		}
+		return metadata, nil
+	}
+}
+
+// fileDigest returns the hex encoded SHA-256 digest of the file.
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// remediationHints maps recognisable failure signatures to the message keys of
+// human-readable advice on how to resolve them. The most specific signatures
+// come first.
+var remediationHints = []struct {
+	sentinels []error
+	statuses  []int
+	fragments []string
+	hint      string
+}{
+	{
+		sentinels: []error{errSelfPublish},
+		hint:      messageHintSelfPublish,
+	},
+	{
+		sentinels: []error{errUnexpectedHead},
+		hint:      messageHintNonFastForward,
+	},
+	{
+		fragments: []string{"protected branch", "gh006", "gh013", "repository rule violations", "rulesets"},
+		hint:      messageHintProtected,
+	},
+	{
+		sentinels: []error{transport.ErrAuthenticationRequired},
+		statuses:  []int{http.StatusUnauthorized},
+		fragments: []string{"401 unauthorized", "authentication required", "invalid username or password"},
+		hint:      messageHintAuthentication,
+	},
+	{
+		sentinels: []error{transport.ErrAuthorizationFailed},
+		statuses:  []int{http.StatusForbidden},
+		fragments: []string{"403 forbidden", "permission to", "denied to"},
+		hint:      messageHintAuthorization,
+	},
+	{
+		sentinels: []error{transport.ErrRepositoryNotFound},
+		fragments: []string{"repository not found"},
+		hint:      messageHintNotFound,
+	},
+	{
+		sentinels: []error{git.ErrNonFastForwardUpdate, git.ErrForceNeeded},
+		fragments: []string{"non-fast-forward"},
+		hint:      messageHintNonFastForward,
+	},
+}
+
+// remediationHint returns advice for resolving err, or an empty string when the
+// failure is not recognised.
+func remediationHint(err error) string {
+	message := strings.ToLower(err.Error())
+
+	var apiErr *githubAPIError
+	isAPIError := errors.As(err, &apiErr)
+
+	for _, remediation := range remediationHints {
+		for _, sentinel := range remediation.sentinels {
+			if errors.Is(err, sentinel) {
+				return msg(remediation.hint)
+			}
+		}
+
+		if isAPIError && slices.Contains(remediation.statuses, apiErr.StatusCode) {
+			return msg(remediation.hint)
+		}
+
+		for _, fragment := range remediation.fragments {
+			if strings.Contains(message, fragment) {
+				return msg(remediation.hint)
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Points in the pipeline at which hook commands can run.
+const (
+	hookAfterClone = "after-clone"
+	hookAfterCopy  = "after-copy"
+	hookAfterPush  = "after-push"
+)
+
+// runHook runs a user supplied shell command inside the prepared worktree.
+// Details about the publish are passed through PUBLISH_DIRECTORY_ variables.
+func runHook(name, command, dir string, cfg Config, commit string) error {
+	if command == "" {
+		return nil
+	}
+
+	fmt.Println(msg(messageRunningHook, name))
+
+	cmd := shellCommand(command)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"PUBLISH_DIRECTORY_HOOK="+name,
+		"PUBLISH_DIRECTORY_BRANCH="+cfg.Branch,
+		"PUBLISH_DIRECTORY_FOLDER="+cfg.Folder,
+		"PUBLISH_DIRECTORY_WORKTREE="+dir,
+		"PUBLISH_DIRECTORY_COMMIT="+commit,
+	)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook failed: %w", name, err)
+	}
+
+	return nil
+}
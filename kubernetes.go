@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// serviceAccountDirectory holds the credentials of the pod's service account.
+const serviceAccountDirectory = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubernetesClient is a minimal client of the Kubernetes API, authenticated
+// with the service account of the pod it runs in.
+type kubernetesClient struct {
+	host   string
+	token  string
+	client *http.Client
+}
+
+// newInClusterClient creates a client from the in-cluster service account.
+func newInClusterClient() (*kubernetesClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running inside a Kubernetes cluster")
+	}
+
+	token, err := os.ReadFile(serviceAccountDirectory + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	certificate, err := os.ReadFile(serviceAccountDirectory + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster certificate: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(certificate) {
+		return nil, fmt.Errorf("invalid cluster certificate")
+	}
+
+	return &kubernetesClient{
+		host:  "https://" + net.JoinHostPort(host, port),
+		token: strings.TrimSpace(string(token)),
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+	}, nil
+}
+
+// request performs a Kubernetes API request. The body, when not nil, is sent
+// as JSON with the content type, and the response is decoded into result,
+// when not nil.
+func (k *kubernetesClient) request(method, path, contentType string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, k.host+path, reader)
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+k.token)
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	response, err := k.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		var failure struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&failure)
+		return fmt.Errorf("%s %s: %d %s", method, path, response.StatusCode, failure.Message)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
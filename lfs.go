@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing/format/gitattributes"
+)
+
+const lfsMediaType = "application/vnd.git-lfs+json"
+
+// lfsPointerVersion is the first line of every LFS pointer file.
+const lfsPointerVersion = "version https://git-lfs.github.com/spec/v1\n"
+
+// lfsMaxPointerSize is the size above which a file is never a pointer.
+const lfsMaxPointerSize = 1024
+
+// lfsObject is a file that has been replaced by an LFS pointer in the working
+// tree. The original content is kept at Path until it has been uploaded.
+type lfsObject struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+	Path string `json:"-"`
+}
+
+// lfsTrackPatterns combines the filter=lfs patterns from the .gitattributes in
+// dir with the additionally configured track patterns.
+func lfsTrackPatterns(dir string, track []string) ([]gitattributes.MatchAttribute, error) {
+	attributes, err := gitattributes.ReadPatterns(osfs.New(dir), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pattern := range track {
+		attribute, err := gitattributes.ParseAttributesLine(pattern+" filter=lfs diff=lfs merge=lfs -text", nil, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LFS track pattern '%s': %w", pattern, err)
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	return attributes, nil
+}
+
+// convertToLFSPointers replaces every file in dir that matches an LFS tracked
+// pattern with a pointer file, moving the content to storage for uploading.
+func convertToLFSPointers(dir, storage string, track []string) ([]lfsObject, error) {
+	attributes, err := lfsTrackPatterns(dir, track)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+
+	if err := writeLFSAttributes(dir, track); err != nil {
+		return nil, err
+	}
+
+	matcher := gitattributes.NewMatcher(attributes)
+
+	var objects []lfsObject
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		results, matched := matcher.Match(splitPath(relativePath), []string{"filter"})
+		if !matched || results["filter"] == nil || results["filter"].Value() != "lfs" {
+			return nil
+		}
+
+		// Files kept from the branch, or published from an LFS checkout, are
+		// pointers already and must not be wrapped in another pointer.
+		pointer, err := isLFSPointer(path, info.Size())
+		if err != nil {
+			return err
+		}
+		if pointer {
+			return nil
+		}
+
+		object, err := storeLFSObject(path, storage)
+		if err != nil {
+			return fmt.Errorf("failed to store '%s' in LFS: %w", relativePath, err)
+		}
+		objects = append(objects, object)
+
+		content := fmt.Sprintf("%soid sha256:%s\nsize %d\n", lfsPointerVersion, object.Oid, object.Size)
+		return os.WriteFile(path, []byte(content), info.Mode())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+// isLFSPointer reports whether the file at path is an LFS pointer file.
+func isLFSPointer(path string, size int64) (bool, error) {
+	if size > lfsMaxPointerSize {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.HasPrefix(string(content), lfsPointerVersion), nil
+}
+
+// storeLFSObject hashes the file at path and moves its content into storage,
+// named after its oid.
+func storeLFSObject(path, storage string) (lfsObject, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return lfsObject{}, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return lfsObject{}, err
+	}
+
+	oid := hex.EncodeToString(hash.Sum(nil))
+	storedPath := filepath.Join(storage, oid)
+
+	if err := copyFile(path, storedPath); err != nil {
+		return lfsObject{}, err
+	}
+
+	return lfsObject{Oid: oid, Size: size, Path: storedPath}, nil
+}
+
+// writeLFSAttributes makes sure the configured track patterns are present in
+// the .gitattributes of the published tree, so clients smudge the pointers.
+func writeLFSAttributes(dir string, track []string) error {
+	if len(track) == 0 {
+		return nil
+	}
+
+	path := filepath.Join(dir, ".gitattributes")
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	content := string(existing)
+	for _, pattern := range track {
+		line := pattern + " filter=lfs diff=lfs merge=lfs -text"
+		if strings.Contains(content, line) {
+			continue
+		}
+
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += line + "\n"
+	}
+
+	return os.WriteFile(path, []byte(content), 0o644)
+}
+
+// lfsBatchResponse is the subset of the LFS batch API response that is needed
+// to upload objects using the basic transfer adapter.
+type lfsBatchResponse struct {
+	Objects []struct {
+		Oid     string `json:"oid"`
+		Size    int64  `json:"size"`
+		Actions map[string]struct {
+			Href   string            `json:"href"`
+			Header map[string]string `json:"header"`
+		} `json:"actions"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+}
+
+// uploadLFSObjects uploads the objects to the LFS endpoint of the remote using
+// the batch API and the basic transfer adapter.
+func uploadLFSObjects(gitURL, username, password string, objects []lfsObject) error {
+	if len(objects) == 0 {
+		return nil
+	}
+
+	endpoint := strings.TrimSuffix(gitURL, ".git") + ".git/info/lfs/objects/batch"
+
+	body, err := json.Marshal(map[string]any{
+		"operation": "upload",
+		"transfers": []string{"basic"},
+		"objects":   objects,
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", lfsMediaType)
+	request.Header.Set("Content-Type", lfsMediaType)
+	request.SetBasicAuth(username, password)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("LFS batch request failed with status %s", response.Status)
+	}
+
+	var batch lfsBatchResponse
+	if err := json.NewDecoder(response.Body).Decode(&batch); err != nil {
+		return fmt.Errorf("failed to decode LFS batch response: %w", err)
+	}
+
+	paths := map[string]string{}
+	for _, object := range objects {
+		paths[object.Oid] = object.Path
+	}
+
+	for _, object := range batch.Objects {
+		if object.Error != nil {
+			return fmt.Errorf("LFS object %s: %s", object.Oid, object.Error.Message)
+		}
+
+		upload, ok := object.Actions["upload"]
+		if !ok {
+			continue
+		}
+
+		if err := uploadLFSObject(upload.Href, upload.Header, paths[object.Oid], object.Size); err != nil {
+			return fmt.Errorf("failed to upload LFS object %s: %w", object.Oid, err)
+		}
+
+		if verify, ok := object.Actions["verify"]; ok {
+			if err := verifyLFSObject(verify.Href, verify.Header, object.Oid, object.Size); err != nil {
+				return fmt.Errorf("failed to verify LFS object %s: %w", object.Oid, err)
+			}
+		}
+	}
+
+	fmt.Println(msg(messageUploadedLFS, len(objects)))
+	return nil
+}
+
+func uploadLFSObject(href string, header map[string]string, path string, size int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	request, err := http.NewRequest(http.MethodPut, href, file)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = size
+	request.Header.Set("Content-Type", "application/octet-stream")
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("upload failed with status %s", response.Status)
+	}
+
+	return nil
+}
+
+func verifyLFSObject(href string, header map[string]string, oid string, size int64) error {
+	body, err := json.Marshal(lfsObject{Oid: oid, Size: size})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, href, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", lfsMediaType)
+	request.Header.Set("Content-Type", lfsMediaType)
+	for key, value := range header {
+		request.Header.Set(key, value)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("verify failed with status %s", response.Status)
+	}
+
+	return nil
+}
+
+// storedLFSObjects lists the objects kept in storage by an earlier run.
+func storedLFSObjects(storage string) ([]lfsObject, error) {
+	if storage == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(storage)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]lfsObject, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, lfsObject{
+			Oid:  entry.Name(),
+			Size: info.Size(),
+			Path: filepath.Join(storage, entry.Name()),
+		})
+	}
+
+	return objects, nil
+}
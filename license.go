@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var spdxIdentifierExpression = regexp.MustCompile(`SPDX-License-Identifier:\s*([A-Za-z0-9.+\-() ]+)`)
+
+// licenseSignatures maps recognisable phrases of full license texts to their
+// SPDX identifiers. More specific signatures are listed first.
+var licenseSignatures = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 2.1"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 2"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"MPL-2.0", []string{"Mozilla Public License Version 2.0"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+}
+
+// isLicenseFile reports whether the file name looks like a license text.
+func isLicenseFile(name string) bool {
+	name = strings.ToUpper(strings.TrimSuffix(name, filepath.Ext(name)))
+	return name == "LICENSE" || name == "LICENCE" || name == "COPYING" || strings.HasPrefix(name, "LICENSE-")
+}
+
+// detectLicenses returns the SPDX identifiers of the licenses declared in the
+// file, either through SPDX-License-Identifier tags or, for license files,
+// by recognising the license text.
+func detectLicenses(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, 64*1024)
+	n, err := bufio.NewReader(file).Read(buffer)
+	if err != nil && n == 0 {
+		return nil, nil
+	}
+	content := string(buffer[:n])
+
+	var licenses []string
+	for _, match := range spdxIdentifierExpression.FindAllStringSubmatch(content, -1) {
+		licenses = append(licenses, strings.TrimSpace(match[1]))
+	}
+
+	if len(licenses) > 0 || !isLicenseFile(filepath.Base(path)) {
+		return licenses, nil
+	}
+
+	for _, signature := range licenseSignatures {
+		matched := true
+		for _, phrase := range signature.phrases {
+			if !strings.Contains(content, phrase) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return []string{signature.id}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// licenseAllowed reports whether a license expression is permitted by the
+// allow and deny lists. An expression is permitted when one of its OR
+// alternatives consists only of allowed, non-denied licenses.
+func licenseAllowed(expression string, allow, deny []string) bool {
+	contains := func(list []string, id string) bool {
+		for _, item := range list {
+			if strings.EqualFold(item, id) {
+				return true
+			}
+		}
+		return false
+	}
+
+	expression = strings.NewReplacer("(", " ", ")", " ").Replace(expression)
+	for _, alternative := range strings.Split(expression, " OR ") {
+		permitted := true
+		for _, id := range strings.Split(alternative, " AND ") {
+			id = strings.TrimSpace(strings.SplitN(id, " WITH ", 2)[0])
+			if contains(deny, id) || (len(allow) > 0 && !contains(allow, id)) {
+				permitted = false
+				break
+			}
+		}
+
+		if permitted {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scanLicenses detects the licenses of the files in dir and reports those not
+// permitted by the allow and deny lists, failing unless action is "warn".
+func scanLicenses(dir string, allow, deny []string, action string) error {
+	var disallowed []string
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		licenses, err := detectLicenses(path)
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		for _, license := range licenses {
+			if licenseAllowed(license, allow, deny) {
+				continue
+			}
+
+			level := "error"
+			if action == "warn" {
+				level = "warning"
+			}
+
+			fmt.Printf("::%s title=License,file=%s::%s\n", level, filepath.ToSlash(relativePath), msg(messageLicenseNotAllowed, license))
+			disallowed = append(disallowed, filepath.ToSlash(relativePath))
+			break
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan licenses: %w", err)
+	}
+
+	if len(disallowed) > 0 && action != "warn" {
+		return fmt.Errorf("%d files carry disallowed licenses: %s", len(disallowed), strings.Join(disallowed, ", "))
+	}
+
+	return nil
+}
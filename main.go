@@ -1,151 +1,1456 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 )
 
 type Config struct {
-	Repository       string `env:"INPUT_REPOSITORY"`
-	Branch           string `env:"INPUT_BRANCH"`
-	Folder           string `env:"INPUT_FOLDER"`
-	CommitUser       string `env:"INPUT_COMMIT_USERNAME" envDefault:"github-actions[bot]"`
-	CommitEmail      string `env:"INPUT_COMMIT_EMAIL" envDefault:"github-actions[bot]@users.noreply.github.com"`
-	CommitMessage    string `env:"INPUT_COMMIT_MESSAGE" envDefault:"chore: update branch from directory"`
-	SkipEmptyCommits bool   `env:"INPUT_SKIP_EMTPY_COMMITS" envDefault:"true"`
-	GithubToken      string `env:"INPUT_GITHUB_TOKEN"`
-	GithubRepository string `env:"GITHUB_REPOSITORY"`
+	Repository           string `env:"INPUT_REPOSITORY"`
+	Branch               string `env:"INPUT_BRANCH"`
+	Folder               string `env:"INPUT_FOLDER"`
+	FolderConflict       string `env:"INPUT_FOLDER_CONFLICT" envDefault:"overwrite"`
+	KeepFiles            string `env:"INPUT_KEEP_FILES"`
+	Clean                bool   `env:"INPUT_CLEAN" envDefault:"true"`
+	RespectGitignore     bool   `env:"INPUT_RESPECT_GITIGNORE"`
+	NoJekyll             bool   `env:"INPUT_NOJEKYLL"`
+	CNAME                string `env:"INPUT_CNAME"`
+	ConfigurePages       bool   `env:"INPUT_CONFIGURE_PAGES"`
+	TriggerPagesBuild    bool   `env:"INPUT_TRIGGER_PAGES_BUILD"`
+	PagesBuildTimeout    string `env:"INPUT_PAGES_BUILD_TIMEOUT"`
+	CommitStatus         bool   `env:"INPUT_COMMIT_STATUS"`
+	DeployEnvironment    string `env:"INPUT_DEPLOYMENT_ENVIRONMENT"`
+	DeploymentURL        string `env:"INPUT_DEPLOYMENT_URL"`
+	PullRequestComment   bool   `env:"INPUT_PR_COMMENT"`
+	PreviewURL           string `env:"INPUT_PREVIEW_URL"`
+	PruneBranches        string `env:"INPUT_PRUNE_BRANCHES"`
+	MaxAge               string `env:"INPUT_MAX_AGE"`
+	Version              string `env:"INPUT_VERSION"`
+	VersionTitle         string `env:"INPUT_VERSION_TITLE"`
+	VersionsFile         string `env:"INPUT_VERSIONS_FILE" envDefault:"versions.json"`
+	VersionsSchema       string `env:"INPUT_VERSIONS_SCHEMA" envDefault:"mike"`
+	KeepVersions         string `env:"INPUT_KEEP_VERSIONS"`
+	Aliases              string `env:"INPUT_ALIASES"`
+	HelmRepoURL          string `env:"INPUT_HELM_REPO_URL"`
+	CommitUser           string `env:"INPUT_COMMIT_USERNAME" envDefault:"github-actions[bot]"`
+	CommitEmail          string `env:"INPUT_COMMIT_EMAIL" envDefault:"github-actions[bot]@users.noreply.github.com"`
+	CommitMessage        string `env:"INPUT_COMMIT_MESSAGE" envDefault:"chore: update branch from directory"`
+	SkipEmptyCommits     bool   `env:"INPUT_SKIP_EMTPY_COMMITS" envDefault:"true"`
+	GithubToken          string `env:"INPUT_GITHUB_TOKEN"`
+	GithubRepository     string `env:"GITHUB_REPOSITORY"`
+	Manifest             string `env:"INPUT_MANIFEST"`
+	MtimeManifest        string `env:"INPUT_MTIME_MANIFEST"`
+	ExportIgnore         bool   `env:"INPUT_RESPECT_EXPORT_IGNORE" envDefault:"false"`
+	LFS                  bool   `env:"INPUT_LFS" envDefault:"false"`
+	LFSTrack             string `env:"INPUT_LFS_TRACK"`
+	LFSOversized         bool   `env:"INPUT_LFS_OVERSIZED" envDefault:"false"`
+	SBOM                 string `env:"INPUT_SBOM"`
+	SBOMPath             string `env:"INPUT_SBOM_PATH"`
+	BadgePath            string `env:"INPUT_BADGE_PATH"`
+	BadgeLabel           string `env:"INPUT_BADGE_LABEL" envDefault:"published"`
+	BadgeVersion         string `env:"INPUT_BADGE_VERSION"`
+	DiagnosticsPath      string `env:"INPUT_DIAGNOSTICS_PATH"`
+	CheckStatus          bool   `env:"INPUT_CHECK_GITHUB_STATUS" envDefault:"false"`
+	Targets              string `env:"INPUT_TARGETS"`
+	StateFile            string `env:"INPUT_STATE_FILE"`
+	Mode                 string `env:"INPUT_MODE" envDefault:"publish"`
+	DriftBackup          string `env:"INPUT_DRIFT_BACKUP_BRANCH"`
+	SizeBudget           string `env:"INPUT_SIZE_BUDGET"`
+	SizeBudgetAction     string `env:"INPUT_SIZE_BUDGET_ACTION" envDefault:"warn"`
+	CommitAsActor        bool   `env:"INPUT_COMMIT_AS_ACTOR" envDefault:"false"`
+	CommitTimezone       string `env:"INPUT_COMMIT_TIMEZONE"`
+	UseSourceDate        bool   `env:"INPUT_USE_SOURCE_DATE" envDefault:"false"`
+	CommitMessageLint    string `env:"INPUT_COMMIT_MESSAGE_LINT"`
+	CommitMessagePattern string `env:"INPUT_COMMIT_MESSAGE_PATTERN"`
+	SnapshotBranch       string `env:"INPUT_SNAPSHOT_BRANCH"`
+	SnapshotOnly         bool   `env:"INPUT_SNAPSHOT_ONLY" envDefault:"false"`
+	BaseURL              string `env:"INPUT_BASE_URL"`
+	URLManifest          string `env:"INPUT_URL_MANIFEST"`
+	Locale               string `env:"INPUT_LOCALE"`
+	HookAfterClone       string `env:"INPUT_HOOK_AFTER_CLONE"`
+	HookAfterCopy        string `env:"INPUT_HOOK_AFTER_COPY"`
+	HookAfterPush        string `env:"INPUT_HOOK_AFTER_PUSH"`
+	RequireApproval      bool   `env:"INPUT_REQUIRE_APPROVAL" envDefault:"false"`
+	ApprovalIssue        int    `env:"INPUT_APPROVAL_ISSUE"`
+	ApprovalTimeout      string `env:"INPUT_APPROVAL_TIMEOUT" envDefault:"30m"`
+	Approvers            string `env:"INPUT_APPROVERS"`
+	PolicyFile           string `env:"INPUT_POLICY_FILE"`
+	ClamAV               bool   `env:"INPUT_CLAMAV" envDefault:"false"`
+	ScanCommand          string `env:"INPUT_SCAN_COMMAND"`
+	LicenseAllow         string `env:"INPUT_LICENSE_ALLOW"`
+	LicenseDeny          string `env:"INPUT_LICENSE_DENY"`
+	LicenseAction        string `env:"INPUT_LICENSE_ACTION" envDefault:"fail"`
+	RequiredPaths        string `env:"INPUT_REQUIRED_PATHS"`
+	SafetyExcludes       bool   `env:"INPUT_SAFETY_EXCLUDES" envDefault:"true"`
+	AllowSelfPublish     bool   `env:"INPUT_ALLOW_SELF_PUBLISH" envDefault:"false"`
+	TokenRefreshCommand  string `env:"INPUT_TOKEN_REFRESH_COMMAND"`
+	CheckRulesets        bool   `env:"INPUT_CHECK_RULESETS" envDefault:"false"`
+	RevertOnFailure      bool   `env:"INPUT_REVERT_ON_FAILURE" envDefault:"false"`
+	ServerConfig         string `env:"INPUT_SERVER_CONFIG"`
+	Listen               string `env:"INPUT_LISTEN" envDefault:":8080"`
+	WebhookSecret        string `env:"INPUT_WEBHOOK_SECRET"`
+	APIToken             string `env:"INPUT_API_TOKEN"`
+	ReconcileInterval    string `env:"INPUT_RECONCILE_INTERVAL" envDefault:"1m"`
+	WatchNamespace       string `env:"INPUT_WATCH_NAMESPACE"`
+	SourcePathRoot       string `env:"INPUT_SOURCE_PATH_ROOT"`
+	TargetDir            string `env:"INPUT_TARGET_DIR"`
+	Exclude              string `env:"INPUT_EXCLUDE"`
+	Include              string `env:"INPUT_INCLUDE"`
+	SingleCommit         bool   `env:"INPUT_SINGLE_COMMIT" envDefault:"false"`
+	DryRun               bool   `env:"INPUT_DRY_RUN" envDefault:"false"`
+	SSHKey               string `env:"INPUT_SSH_KEY"`
+	SSHKnownHosts        string `env:"INPUT_SSH_KNOWN_HOSTS"`
+	AppID                string `env:"INPUT_APP_ID"`
+	AppPrivateKey        string `env:"INPUT_APP_PRIVATE_KEY"`
+	GithubServerURL      string `env:"INPUT_GITHUB_SERVER_URL"`
+	GithubAPIURL         string `env:"INPUT_GITHUB_API_URL"`
+	Provider             string `env:"INPUT_PROVIDER"`
+	GitlabToken          string `env:"INPUT_GITLAB_TOKEN"`
+	GitlabURL            string `env:"INPUT_GITLAB_URL"`
+	BitbucketUsername    string `env:"INPUT_BITBUCKET_USERNAME"`
+	BitbucketToken       string `env:"INPUT_BITBUCKET_TOKEN"`
+	GiteaURL             string `env:"INPUT_GITEA_URL"`
+	GiteaUsername        string `env:"INPUT_GITEA_USERNAME"`
+	GiteaToken           string `env:"INPUT_GITEA_TOKEN"`
+	AzureDevOpsURL       string `env:"INPUT_AZURE_DEVOPS_URL" envDefault:"https://dev.azure.com"`
+	AzureDevOpsToken     string `env:"INPUT_AZURE_DEVOPS_TOKEN"`
+	RemoteURL            string `env:"INPUT_REMOTE_URL"`
+	RemoteUsername       string `env:"INPUT_REMOTE_USERNAME"`
+	RemotePassword       string `env:"INPUT_REMOTE_PASSWORD"`
+	GPGPrivateKey        string `env:"INPUT_GPG_PRIVATE_KEY"`
+	GPGPassphrase        string `env:"INPUT_GPG_PASSPHRASE"`
+	SSHSigningKey        string `env:"INPUT_SSH_SIGNING_KEY"`
+	SSHSigningPassphrase string `env:"INPUT_SSH_SIGNING_PASSPHRASE"`
+	SignOff              bool   `env:"INPUT_SIGNOFF" envDefault:"false"`
+	CommitMessageFile    string `env:"INPUT_COMMIT_MESSAGE_FILE"`
+	CoAuthorFromSource   bool   `env:"INPUT_CO_AUTHOR_FROM_SOURCE" envDefault:"false"`
+	SourceTrailers       bool   `env:"INPUT_SOURCE_TRAILERS" envDefault:"true"`
+	UseActorIdentity     bool   `env:"INPUT_USE_ACTOR_IDENTITY" envDefault:"false"`
+	CommitterName        string `env:"INPUT_COMMITTER_NAME"`
+	CommitterEmail       string `env:"INPUT_COMMITTER_EMAIL"`
+	CommitDate           string `env:"INPUT_COMMIT_DATE"`
+	SourceDateEpoch      string `env:"SOURCE_DATE_EPOCH"`
+	PushRetries          int    `env:"INPUT_PUSH_RETRIES" envDefault:"3"`
+	PushRetryDelay       string `env:"INPUT_PUSH_RETRY_DELAY" envDefault:"2s"`
+	RebaseRetries        int    `env:"INPUT_REBASE_RETRIES" envDefault:"0"`
+	Force                bool   `env:"INPUT_FORCE" envDefault:"false"`
+	ExpectedHeadSHA      string `env:"INPUT_EXPECTED_HEAD_SHA"`
+	Tag                  string `env:"INPUT_TAG"`
+	TagMessage           string `env:"INPUT_TAG_MESSAGE"`
+	SignTag              bool   `env:"INPUT_SIGN_TAG" envDefault:"false"`
+	RefType              string `env:"INPUT_REF_TYPE" envDefault:"branch"`
+	ReleaseTag           string `env:"INPUT_RELEASE_TAG"`
+	ReleaseFormats       string `env:"INPUT_RELEASE_FORMATS" envDefault:"tar.gz"`
+	ConfigFile           string `env:"INPUT_CONFIG_FILE"`
+
+	// AssumeYes skips confirmation prompts and is set with the --yes flag.
+	AssumeYes bool
+	// PullRequestBranch is the branch a publish is pushed to and proposed
+	// from when rulesets reject direct pushes to the target branch.
+	PullRequestBranch string
+	// AdditionalBranches receive the tree published to Branch as well, when
+	// a list of branches is configured.
+	AdditionalBranches []string
+}
+
+// Modes the action can run in.
+const (
+	modePublish  = "publish"
+	modeDrift    = "drift"
+	modeRepair   = "repair"
+	modeCheck    = "check"
+	modeVerify   = "verify"
+	modeServer   = "server"
+	modeOperator = "operator"
+	modeRelease  = "release"
+	modeCleanup  = "cleanup"
+	modePrune    = "prune"
+	modeHelm     = "helm"
+)
+
+func main() {
+	assumeYes := flag.Bool("yes", false, "skip confirmation prompts for destructive operations")
+	describe := flag.Bool("describe", false, "print a JSON schema of the supported inputs and exit")
+	jsonOutputs := flag.Bool("json", false, "write the outputs to stdout as JSON lines and the log to stderr")
+	flag.Parse()
+
+	if *describe {
+		if err := describeInputs(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *jsonOutputs {
+		enableJSONOutput()
+	}
+
+	if args := flag.Args(); len(args) > 1 && args[0] == "restore-mtimes" {
+		root := "."
+		if len(args) > 2 {
+			root = args[2]
+		}
+
+		if err := restoreMtimes(args[1], root); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, msg(messageLoadConfigFailed, err))
+		os.Exit(1)
+	}
+	config.AssumeYes = *assumeYes
+	setLocale(config.Locale)
+
+	if err := applyGithubURLs(config.GithubServerURL, config.GithubAPIURL); err != nil {
+		fmt.Fprintln(os.Stderr, msg(messageLoadConfigFailed, err))
+		os.Exit(1)
+	}
+
+	if strings.Contains(config.Branch, "{pr_number}") && !isPullRequestEvent() {
+		fmt.Fprintln(os.Stderr, msg(messageConfigError, fmt.Errorf("branch '%s' contains {pr_number} but the workflow was not triggered by a pull request", config.Branch)))
+		os.Exit(1)
+	}
+	config.Branch = expandTemplate(config.Branch, time.Now())
+	config.Version = expandTemplate(config.Version, time.Now())
+
+	// The server and operator modes outlive installation tokens and mint
+	// one for every job instead.
+	if config.AppID != "" && config.Mode != modeServer && config.Mode != modeOperator {
+		repository, err := resolveRepository(config)
+		if err == nil {
+			config.GithubToken, err = installationToken(config, repository)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+	}
+
+	if os.Getenv(postStateVariable) != "" {
+		if err := runPostStep(config); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The post step runs after every main step, whatever it did, and must not
+	// repeat its work.
+	if err := saveActionState("post", "true"); err != nil {
+		fmt.Fprintln(os.Stderr, msg(messageError), err)
+		os.Exit(1)
+	}
+
+	if err := validateConfig(config); err != nil {
+		fmt.Fprintln(os.Stderr, msg(messageConfigError, err))
+		os.Exit(1)
+	}
+
+	if config.Mode == modeDrift || config.Mode == modeRepair {
+		if err := checkDrift(config); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Mode == modeOperator {
+		if err := runOperator(config); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Mode == modeServer {
+		if err := serve(config); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Mode == modeVerify {
+		if err := verifyBranch(config); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Mode == modeRelease {
+		repository, err := resolveRepository(config)
+		if err == nil {
+			err = publishRelease(config, repository, os.Getenv("GITHUB_SHA"))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Mode == modeCleanup {
+		if err := cleanupBranch(config); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Mode == modePrune {
+		if err := pruneBranches(config); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Mode == modeCheck {
+		if err := checkBranch(config); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageError), err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// A chart repository accumulates charts, with the index listing every
+	// chart that was ever published.
+	if config.Mode == modeHelm {
+		config.Clean = false
+	}
+
+	if config.SnapshotBranch != "" {
+		config.SnapshotBranch = expandTemplate(config.SnapshotBranch, time.Now())
+		if config.SnapshotOnly {
+			config.Branch, config.SnapshotBranch = config.SnapshotBranch, ""
+		}
+	}
+
+	// The same content is published to every branch of a list, from a single
+	// clone of the repository.
+	if branches := splitList(config.Branch); len(branches) > 1 && config.Targets == "" {
+		config.Branch, config.AdditionalBranches = branches[0], branches[1:]
+	}
+
+	if config.Targets != "" {
+		targets, err := parseTargets(config.Targets)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageConfigError, err))
+			os.Exit(1)
+		}
+
+		os.Exit(publishTargets(config, targets))
+	}
+
+	diag := newDiagnostics(config.DiagnosticsPath != "")
+
+	if err := publishDirectory(config, diag); err != nil {
+		reportError(config, diag, err)
+		os.Exit(1)
+	}
+
+	if config.DryRun {
+		fmt.Println(colorize(colorGreen, msg(messageDryRunComplete)))
+		return
+	}
+
+	if err := clearState(config.StateFile); err != nil {
+		fmt.Fprintln(os.Stderr, msg(messageRemoveStateFailed, err))
+	}
+
+	fmt.Println(colorize(colorGreen, msg(messagePublished)))
+}
+
+// reportError prints a failed publish along with a remediation hint, and
+// writes the diagnostics bundle when configured.
+func reportError(cfg Config, diag *diagnostics, err error) {
+	fmt.Fprintln(os.Stderr, colorize(colorRed, msg(messageError)), err)
+
+	if hint := remediationHint(err); hint != "" {
+		fmt.Fprintln(os.Stderr, msg(messageHint, hint))
+	}
+
+	if cfg.DiagnosticsPath != "" {
+		if err := diag.write(cfg.DiagnosticsPath, cfg, err); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageDiagnosticsFailed, err))
+		}
+	}
+}
+
+func loadConfig() (Config, error) {
+	cfg := Config{}
+
+	environment, err := configEnvironment()
+	if err != nil {
+		return cfg, err
+	}
+
+	for _, name := range unknownInputs(environment) {
+		fmt.Printf("::warning title=Configuration::%s\n", msg(messageUnknownInput, name))
+	}
+
+	if err := env.ParseWithOptions(&cfg, env.Options{Environment: environment}); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// splitList splits a newline or comma separated input into its trimmed,
+// non-empty items.
+func splitList(input string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isBranchPath reports whether a slash separated path stays inside the
+// branch without reaching into its .git directory.
+func isBranchPath(name string) bool {
+	first, _, _ := strings.Cut(filepath.ToSlash(filepath.Clean(filepath.FromSlash(name))), "/")
+	return filepath.IsLocal(filepath.FromSlash(name)) && !strings.EqualFold(first, git.GitDirName)
+}
+
+func validateConfig(cfg Config) error {
+	switch cfg.Mode {
+	case modePublish, modeVerify, modeRelease, modeHelm:
+	case modeDrift, modeRepair, modeCheck, modeCleanup:
+		return nil
+	case modeOperator:
+		if _, err := time.ParseDuration(cfg.ReconcileInterval); err != nil {
+			return fmt.Errorf("invalid reconcile interval: %w", err)
+		}
+		return nil
+	case modePrune:
+		if cfg.PruneBranches == "" || cfg.MaxAge == "" {
+			return fmt.Errorf("prune mode requires prune_branches and max_age")
+		}
+		if _, err := parseAge(cfg.MaxAge); err != nil {
+			return err
+		}
+		return nil
+	case modeServer:
+		if cfg.ServerConfig == "" || cfg.WebhookSecret == "" {
+			return fmt.Errorf("server mode requires server_config and webhook_secret")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown mode '%s'", cfg.Mode)
+	}
+
+	if provider := remoteProvider(cfg); providers[provider] == nil {
+		return fmt.Errorf("unknown provider '%s'", provider)
+	}
+
+	folders := sourceFolders(cfg)
+	if len(folders) == 0 {
+		return fmt.Errorf("folder is required")
+	}
+
+	for _, folder := range folders {
+		if _, err := os.Stat(folder.Path); os.IsNotExist(err) {
+			return fmt.Errorf("folder '%s' does not exist", folder.Path)
+		}
+
+		if folder.Target != "" && !isBranchPath(folder.Target) {
+			return fmt.Errorf("folder target '%s' must be a relative path inside the branch and outside .git", folder.Target)
+		}
+	}
+
+	if (len(folders) > 1 || folders[0].Target != "") && cfg.Manifest != "" {
+		return fmt.Errorf("manifest cannot be combined with several or mapped folders")
+	}
+
+	switch cfg.FolderConflict {
+	case folderConflictOverwrite, folderConflictSkip, folderConflictError:
+	default:
+		return fmt.Errorf("folder_conflict must be 'overwrite', 'skip' or 'error', got '%s'", cfg.FolderConflict)
+	}
+
+	if cfg.GPGPrivateKey != "" && cfg.SSHSigningKey != "" {
+		return fmt.Errorf("gpg_private_key and ssh_signing_key cannot both be set")
+	}
+
+	if strings.ContainsAny(cfg.CNAME, " /\n") {
+		return fmt.Errorf("cname '%s' must be a domain name", cfg.CNAME)
+	}
+
+	if cfg.Version != "" && !isVersionName(cfg.Version) {
+		return fmt.Errorf("version '%s' must be a single directory name", cfg.Version)
+	}
+
+	for _, alias := range splitList(cfg.Aliases) {
+		if cfg.Version == "" {
+			return fmt.Errorf("aliases require a version")
+		}
+
+		if alias == cfg.Version || !isVersionName(alias) {
+			return fmt.Errorf("alias '%s' must be a single directory name other than the version", alias)
+		}
+	}
+
+	if cfg.KeepVersions != "" {
+		if _, err := versionRetention(cfg.KeepVersions); err != nil {
+			return err
+		}
+	}
+
+	if cfg.VersionsSchema != versionsSchemaMike && cfg.VersionsSchema != versionsSchemaList {
+		return fmt.Errorf("versions_schema must be 'mike' or 'list', got '%s'", cfg.VersionsSchema)
+	}
+
+	if cfg.Version != "" && !isBranchPath(cfg.VersionsFile) {
+		return fmt.Errorf("versions_file '%s' must be a relative path inside the branch and outside .git", cfg.VersionsFile)
+	}
+
+	if cfg.TargetDir != "" && !isBranchPath(cfg.TargetDir) {
+		return fmt.Errorf("target_dir '%s' must be a relative path inside the branch and outside .git", cfg.TargetDir)
+	}
+
+	if cfg.MtimeManifest != "" && !isBranchPath(cfg.MtimeManifest) {
+		return fmt.Errorf("mtime_manifest '%s' must be a relative path inside the branch and outside .git", cfg.MtimeManifest)
+	}
+
+	if cfg.Mode == modeRelease && cfg.ReleaseTag == "" {
+		return fmt.Errorf("release mode requires release_tag")
+	}
+
+	// Formats are checked up front, as the archives are only created after
+	// the branch is pushed.
+	if cfg.ReleaseTag != "" {
+		for _, format := range splitList(cfg.ReleaseFormats) {
+			if !slices.Contains(archiveFormats, format) {
+				return fmt.Errorf("unknown release format '%s', expected tar.gz or zip", format)
+			}
+		}
+	}
+
+	if cfg.Mode == modeVerify || cfg.Mode == modeRelease {
+		return nil
+	}
+
+	if workspace := os.Getenv("GITHUB_WORKSPACE"); workspace != "" {
+		for _, folder := range folders {
+			if err := checkWorkspaceContainment(folder.Path, workspace); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.Manifest != "" {
+		if _, err := os.Stat(cfg.Manifest); os.IsNotExist(err) {
+			return fmt.Errorf("manifest '%s' does not exist", cfg.Manifest)
+		}
+	}
+
+	var missing []string
+	for _, path := range splitList(cfg.RequiredPaths) {
+		found := false
+		for _, folder := range folders {
+			relativePath, ok := strings.CutPrefix(path, folder.Target+"/")
+			if folder.Target == "" {
+				relativePath, ok = path, true
+			}
+
+			if _, err := os.Stat(filepath.Join(folder.Path, filepath.FromSlash(relativePath))); ok && err == nil {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, path)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("required paths missing from folder '%s': %s", cfg.Folder, strings.Join(missing, ", "))
+	}
+
+	if _, err := time.ParseDuration(cfg.ApprovalTimeout); err != nil {
+		return fmt.Errorf("invalid approval timeout: %w", err)
+	}
+
+	if cfg.PagesBuildTimeout != "" {
+		if _, err := time.ParseDuration(cfg.PagesBuildTimeout); err != nil {
+			return fmt.Errorf("invalid pages build timeout: %w", err)
+		}
+	}
+
+	if cfg.CommitMessageLint != "" && cfg.CommitMessageLint != "conventional" {
+		return fmt.Errorf("commit_message_lint must be 'conventional', got '%s'", cfg.CommitMessageLint)
+	}
+
+	if cfg.CommitMessagePattern != "" {
+		if _, err := regexp.Compile(cfg.CommitMessagePattern); err != nil {
+			return fmt.Errorf("invalid commit message pattern: %w", err)
+		}
+	}
+
+	if cfg.CommitTimezone != "" {
+		if _, err := time.LoadLocation(cfg.CommitTimezone); err != nil {
+			return fmt.Errorf("invalid commit timezone '%s': %w", cfg.CommitTimezone, err)
+		}
+	}
+
+	if cfg.SignTag && (cfg.TagMessage == "" || cfg.GPGPrivateKey == "") {
+		return fmt.Errorf("sign_tag requires tag_message and gpg_private_key, as only annotated tags can be signed")
+	}
+
+	switch cfg.RefType {
+	case refTypeBranch:
+	case refTypeTag:
+		if cfg.Tag == "" {
+			return fmt.Errorf("ref_type tag requires the tag to publish to")
+		}
+	default:
+		return fmt.Errorf("unknown ref_type '%s', expected branch or tag", cfg.RefType)
+	}
+
+	if cfg.ExpectedHeadSHA != "" && !plumbing.IsHash(cfg.ExpectedHeadSHA) {
+		return fmt.Errorf("expected_head_sha '%s' must be a full commit SHA", cfg.ExpectedHeadSHA)
+	}
+
+	if cfg.CommitDate != "" {
+		if _, err := parseCommitDate(cfg.CommitDate); err != nil {
+			return err
+		}
+	}
+
+	if delay, err := time.ParseDuration(cfg.PushRetryDelay); err != nil || delay <= 0 || cfg.PushRetries < 0 {
+		return fmt.Errorf("push_retries must not be negative and push_retry_delay must be a positive duration, e.g. 2s")
+	}
+
+	if cfg.SizeBudget != "" {
+		if _, err := parseSize(cfg.SizeBudget); err != nil {
+			return err
+		}
+	}
+
+	if cfg.SizeBudgetAction != "warn" && cfg.SizeBudgetAction != "fail" && cfg.SizeBudgetAction != "squash" {
+		return fmt.Errorf("size_budget_action must be 'warn', 'fail' or 'squash', got '%s'", cfg.SizeBudgetAction)
+	}
+
+	if cfg.LicenseAction != "warn" && cfg.LicenseAction != "fail" {
+		return fmt.Errorf("license_action must be 'warn' or 'fail', got '%s'", cfg.LicenseAction)
+	}
+
+	if cfg.SBOM != "" && cfg.SBOM != "cyclonedx" && cfg.SBOM != "spdx" {
+		return fmt.Errorf("sbom must be 'cyclonedx' or 'spdx', got '%s'", cfg.SBOM)
+	}
+
+	if cfg.SBOMPath != "" && !isBranchPath(cfg.SBOMPath) {
+		return fmt.Errorf("sbom_path '%s' must be a relative path inside the branch and outside .git", cfg.SBOMPath)
+	}
+
+	if cfg.BadgePath != "" && !isBranchPath(cfg.BadgePath) {
+		return fmt.Errorf("badge_path '%s' must be a relative path inside the branch and outside .git", cfg.BadgePath)
+	}
+
+	return nil
+}
+
+// publishDirectory publishes the folder to the branch. When the branch moves
+// while publishing, the publish starts over on its new tip as often as
+// configured.
+func publishDirectory(cfg Config, diag *diagnostics) error {
+	if cfg.DeployEnvironment == "" || cfg.DryRun {
+		return publishAttempts(cfg, diag)
+	}
+
+	repository, err := resolveRepository(cfg)
+	if err != nil {
+		return err
+	}
+
+	return deploy(cfg, repository, func() error {
+		return publishAttempts(cfg, diag)
+	})
+}
+
+// publishAttempts publishes the directory, starting over on the new tip of the
+// branch up to the configured number of times when it moves during a publish.
+func publishAttempts(cfg Config, diag *diagnostics) error {
+	for attempt := 0; ; attempt++ {
+		err := publishAttempt(cfg, diag)
+		if err == nil || attempt >= cfg.RebaseRetries || !isNonFastForward(err) {
+			return err
+		}
+
+		fmt.Println(msg(messageRebasing, cfg.Branch, attempt+1, cfg.RebaseRetries))
+	}
 }
 
-func main() {
-	config, err := loadConfig()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
-		os.Exit(1)
+func publishAttempt(cfg Config, diag *diagnostics) error {
+	diag.phase("prepare")
+
+	repository, url, auth, err := resolveRemote(cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, branch := range append([]string{cfg.Branch}, cfg.AdditionalBranches...) {
+		if !cfg.AllowSelfPublish && isWorkflowBranch(repository, branch) {
+			return fmt.Errorf("%w: '%s' in %s", errSelfPublish, branch, repository)
+		}
+	}
+
+	// Rulesets that reject direct pushes are satisfied by publishing through
+	// a pull request instead.
+	if cfg.CheckRulesets && cfg.RefType == refTypeBranch {
+		if violations := rulesetViolations(cfg, repository); len(violations) > 0 {
+			cfg.PullRequestBranch = rulesetBranchPrefix + cfg.Branch
+			fmt.Println(msg(messageRulesetPullRequest, cfg.Branch, strings.Join(violations, "; "), cfg.PullRequestBranch))
+		}
+	}
+
+	message, err := commitMessage(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cfg.CommitAsActor:
+		cfg.CommitUser, cfg.CommitEmail, err = actorIdentity(cfg.GithubToken)
+	case cfg.UseActorIdentity:
+		cfg.CommitUser, cfg.CommitEmail, err = actorEnvironmentIdentity()
+	}
+	if err != nil {
+		return err
+	}
+
+	trailers, err := commitTrailers(cfg)
+	if err != nil {
+		return err
+	}
+
+	stateKey := target{Repository: repository, Branch: cfg.Branch}.String()
+
+	state, err := loadTargetState(cfg.StateFile, stateKey)
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+
+	var repo *git.Repository
+	var lfsObjects []lfsObject
+	changes := "(resumed from an earlier run)\n"
+
+	if state.resumable() && !cfg.DryRun {
+		fmt.Println(msg(messageResuming, stateKey, state.Phase))
+
+		repo, err = git.PlainOpen(state.Directory)
+		if err != nil {
+			return fmt.Errorf("failed to open prepared repository: %w", err)
+		}
+
+		lfsObjects, err = storedLFSObjects(state.LFSStorage)
+		if err != nil {
+			return fmt.Errorf("failed to load stored LFS objects: %w", err)
+		}
+	} else {
+		workDirectory, lfsStorage, cleanup, err := createWorkDirectories(cfg.StateFile, stateKey)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		repo, lfsObjects, err = prepareWorktree(cfg, repository, url, auth, workDirectory, lfsStorage, diag)
+		if err != nil {
+			return err
+		}
+
+		state.Directory = workDirectory
+		state.LFSStorage = lfsStorage
+		state.Phase = phasePrepared
+		if !cfg.DryRun {
+			if err := saveTargetState(cfg.StateFile, stateKey, state); err != nil {
+				return fmt.Errorf("failed to save state: %w", err)
+			}
+		}
+	}
+
+	if state.Phase == phasePrepared {
+		diag.phase("commit")
+
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return fmt.Errorf("failed to get worktree: %w", err)
+		}
+
+		status, err := worktree.Status()
+		if err != nil {
+			return fmt.Errorf("failed to get status: %w", err)
+		}
+
+		if err := ignoreModeChanges(repo, status); err != nil {
+			return fmt.Errorf("failed to get status: %w", err)
+		}
+
+		if cfg.ExpectedHeadSHA != "" {
+			if err := checkExpectedHead(repo, cfg.Branch, plumbing.NewHash(cfg.ExpectedHeadSHA)); err != nil {
+				return err
+			}
+		}
+
+		squash := cfg.SingleCommit
+		if !squash && cfg.SizeBudget != "" && cfg.SizeBudgetAction == "squash" {
+			exceeded, message, err := exceedsSizeBudget(cfg, state.Directory)
+			if err != nil {
+				return err
+			}
+
+			if exceeded {
+				fmt.Println(msg(messageSquashing, message))
+				squash = true
+			}
+		}
+
+		// A squash of a branch with history is a change even when the
+		// content is the same.
+		if squash && hasHistory(repo) {
+			fmt.Println(msg(messageReplacingHistory))
+		} else if !hasChanges(status, generatedPaths(cfg)) {
+			if cfg.SkipEmptyCommits {
+				fmt.Println(msg(messageNoChanges))
+				if cfg.DryRun {
+					return nil
+				}
+				if err := pushAdditionalBranches(cfg, repo, auth, repository, appendTrailers(message, trailers...), false); err != nil {
+					return err
+				}
+
+				// A tag is still cut from the unchanged content, so re-runs
+				// can snapshot it under a new name.
+				pushed := false
+				if cfg.Tag != "" {
+					head, err := repo.Head()
+					if err != nil {
+						return fmt.Errorf("failed to resolve published commit: %w", err)
+					}
+
+					tagPushed, err := pushTag(cfg, repo, auth, head.Hash())
+					if err != nil {
+						return err
+					}
+					pushed = tagPushed && cfg.RefType == refTypeTag
+				}
+
+				state.Phase = phasePushed
+				if err := saveTargetState(cfg.StateFile, stateKey, state); err != nil {
+					return err
+				}
+				return setPublishOutputs(repo, cfg.Branch, pushed, changeStats{})
+			}
+			fmt.Println(msg(messageEmptyCommit))
+		}
+
+		changes = describeStatus(status)
+		state.Changes, err = statusStats(status, state.Directory)
+		if err != nil {
+			return fmt.Errorf("failed to collect change statistics: %w", err)
+		}
+
+		if cfg.DryRun {
+			fmt.Print(msg(messageDryRunChanges, target{Repository: repository, Branch: cfg.Branch}, changes))
+			fmt.Println(msg(messageDryRunMessage, appendTrailers(message, trailers...)))
+			return nil
+		}
+
+		// Only publishes that rewrite the history of the branch are
+		// destructive enough to ask for confirmation.
+		if cfg.Force || squash {
+			if err := confirm(cfg, msg(messageConfirmPublish, cfg.Branch, repository, changes)); err != nil {
+				return err
+			}
+		}
+
+		when, err := commitTime(cfg)
+		if err != nil {
+			return err
+		}
+
+		signKey, err := gpgSignKey(cfg)
+		if err != nil {
+			return err
+		}
+
+		signer, err := sshSigner(cfg)
+		if err != nil {
+			return err
+		}
+
+		if squash {
+			// Without a branch to extend the commit is created without parents.
+			if err := repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(cfg.Branch)); err != nil {
+				return fmt.Errorf("failed to reset branch: %w", err)
+			}
+		}
+
+		author, committer := commitSignatures(cfg, when)
+		commit, err := worktree.Commit(appendTrailers(message, trailers...), &git.CommitOptions{
+			Author:            author,
+			Committer:         committer,
+			AllowEmptyCommits: !cfg.SkipEmptyCommits,
+			SignKey:           signKey,
+			Signer:            signer,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to commit: %w", err)
+		}
+
+		fmt.Println(msg(messageCreatedCommit, commit.String()))
+
+		if cfg.SnapshotBranch != "" {
+			snapshot := plumbing.NewHashReference(plumbing.NewBranchReferenceName(cfg.SnapshotBranch), commit)
+			if err := repo.Storer.SetReference(snapshot); err != nil {
+				return fmt.Errorf("failed to create snapshot branch: %w", err)
+			}
+			fmt.Println(msg(messageSnapshotBranch, cfg.SnapshotBranch))
+		}
+
+		commitObject, err := repo.CommitObject(commit)
+		if err != nil {
+			return fmt.Errorf("failed to read commit: %w", err)
+		}
+
+		state.Phase = phaseCommitted
+		state.Orphan = squash
+		state.Commit = commit.String()
+		state.Tree = commitObject.TreeHash.String()
+		if err := saveTargetState(cfg.StateFile, stateKey, state); err != nil {
+			return fmt.Errorf("failed to save state: %w", err)
+		}
 	}
 
-	if err := validateConfig(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
-		os.Exit(1)
+	if cfg.SizeBudget != "" && cfg.SizeBudgetAction != "squash" && state.Directory != "" {
+		if err := checkSizeBudget(cfg, state.Directory); err != nil {
+			return err
+		}
 	}
 
-	if err := publishDirectory(config); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	if cfg.RequireApproval {
+		diag.phase("approval")
+
+		if err := waitForApproval(cfg, changes); err != nil {
+			return err
+		}
 	}
 
-	fmt.Println("Successfully published directory to branch")
-}
+	diag.phase("push")
 
-func loadConfig() (Config, error) {
-	cfg := Config{}
-	if err := env.Parse(&cfg); err != nil {
-		return cfg, err
+	if len(lfsObjects) > 0 {
+		basic, ok := auth.(*http.BasicAuth)
+		if !ok {
+			return fmt.Errorf("uploading LFS objects requires token authentication, not an SSH key")
+		}
+
+		if err := uploadLFSObjects(url, basic.Username, basic.Password, lfsObjects); err != nil {
+			return fmt.Errorf("failed to upload LFS objects: %w", err)
+		}
 	}
-	return cfg, nil
-}
 
-func validateConfig(cfg Config) error {
-	if _, err := os.Stat(cfg.Folder); os.IsNotExist(err) {
-		return fmt.Errorf("folder '%s' does not exist", cfg.Folder)
+	// Publishing to a tag leaves the branch untouched and pushes the commit
+	// as the tag only.
+	pushed := true
+	if cfg.RefType == refTypeBranch {
+		pushed, err = pushBranch(cfg, repo, auth, state, stateKey, repository)
+		if err != nil {
+			return err
+		}
+
+		if err := pushAdditionalBranches(cfg, repo, auth, repository, appendTrailers(message, trailers...), state.Orphan); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	state.Phase = phasePushed
+	if err := saveTargetState(cfg.StateFile, stateKey, state); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	if cfg.Tag != "" {
+		head, err := repo.Head()
+		if err != nil {
+			return fmt.Errorf("failed to resolve published commit: %w", err)
+		}
+
+		tagPushed, err := pushTag(cfg, repo, auth, head.Hash())
+		if err != nil {
+			return err
+		}
+		if cfg.RefType == refTypeTag {
+			pushed = tagPushed
+		}
+	}
+
+	if cfg.ConfigurePages && cfg.RefType == refTypeBranch {
+		if err := configurePages(cfg, repository); err != nil {
+			return err
+		}
+	}
+
+	if cfg.TriggerPagesBuild && cfg.RefType == refTypeBranch {
+		head, err := repo.Head()
+		if err != nil {
+			return fmt.Errorf("failed to resolve published commit: %w", err)
+		}
+
+		if err := triggerPagesBuild(cfg, repository, head.Hash().String()); err != nil {
+			return err
+		}
+	}
+
+	if cfg.ReleaseTag != "" {
+		head, err := repo.Head()
+		if err != nil {
+			return fmt.Errorf("failed to resolve published commit: %w", err)
+		}
+
+		if err := publishRelease(cfg, repository, head.Hash().String()); err != nil {
+			return err
+		}
+	}
+
+	if cfg.PullRequestComment {
+		if err := commentOnPullRequest(cfg, repository, state.Commit, state.Changes); err != nil {
+			return err
+		}
+	}
+
+	if cfg.CommitStatus {
+		if err := setSourceStatus(cfg, repository, state.Commit); err != nil {
+			return err
+		}
+	}
+
+	if err := setPublishOutputs(repo, cfg.Branch, pushed, state.Changes); err != nil {
+		return err
+	}
+
+	if err := writeStepSummary(repository, cfg.Branch, state.Commit, pushed, state.Changes); err != nil {
+		return fmt.Errorf("failed to write step summary: %w", err)
+	}
+
+	if cfg.BaseURL != "" {
+		if err := writeURLManifest(cfg, state.Directory, repository); err != nil {
+			return fmt.Errorf("failed to write URL manifest: %w", err)
+		}
+	}
+
+	return runHook(hookAfterPush, cfg.HookAfterPush, state.Directory, cfg, state.Commit)
 }
 
-func publishDirectory(cfg Config) error {
-	repository := cfg.Repository
-	if repository == "" {
-		var err error
-		repository, err = getCurrentRepository()
+// pushBranch pushes the publish commit to the branch and records the publish
+// for the post step. It reports whether anything was pushed.
+func pushBranch(cfg Config, repo *git.Repository, auth transport.AuthMethod, state *targetState, stateKey, repository string) (bool, error) {
+	// With an expected head the push only succeeds when the remote branch is
+	// still at that commit, like git push --force-with-lease.
+	var lease *git.ForceWithLease
+	if cfg.ExpectedHeadSHA != "" && !plumbing.NewHash(cfg.ExpectedHeadSHA).IsZero() {
+		lease = &git.ForceWithLease{
+			RefName: plumbing.NewBranchReferenceName(cfg.Branch),
+			Hash:    plumbing.NewHash(cfg.ExpectedHeadSHA),
+		}
+	}
+
+	// A publish proposed through a pull request replaces the branch it is
+	// proposed from, which the action owns, and leaves the target untouched.
+	branch, force, refSpecs := cfg.Branch, state.Orphan || cfg.Force, []config.RefSpec(nil)
+	if cfg.PullRequestBranch != "" {
+		branch, force, lease = cfg.PullRequestBranch, true, nil
+		refSpecs = []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", plumbing.NewBranchReferenceName(cfg.Branch), plumbing.NewBranchReferenceName(branch)))}
+	}
+
+	push := func() error {
+		stop := spinner(msg(messagePushing, branch))
+		defer stop()
+
+		return repo.Push(&git.PushOptions{
+			RemoteName:     "origin",
+			RefSpecs:       refSpecs,
+			Auth:           auth,
+			Progress:       progressWriter(),
+			Force:          force,
+			ForceWithLease: lease,
+		})
+	}
+
+	previous := plumbing.ZeroHash
+	if reference, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true); err == nil && cfg.PullRequestBranch == "" {
+		previous = reference.Hash()
+	}
+
+	err := retryPush(cfg, push)
+	if basic, ok := auth.(*http.BasicAuth); ok && isExpiredCredential(err) && (cfg.TokenRefreshCommand != "" || cfg.AppID != "") {
+		if err := refreshToken(&cfg, basic); err != nil {
+			return false, err
+		}
+		err = retryPush(cfg, push)
+	}
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		// The tip of a branch that moved since the shallow clone is missing
+		// when checking whether the push fast-forwards.
+		err = fmt.Errorf("%w: the branch moved since it was cloned", git.ErrNonFastForwardUpdate)
+	}
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		// The prepared commit can no longer be pushed, so a re-run has to
+		// start over instead of resuming.
+		if isNonFastForward(err) {
+			if err := saveTargetState(cfg.StateFile, stateKey, &targetState{}); err != nil {
+				return false, fmt.Errorf("failed to save state: %w", err)
+			}
+		}
+
+		if checksGithubStatus(cfg) {
+			err = incidentError(err)
+		}
+		return false, fmt.Errorf("failed to push: %w", err)
+	}
+
+	pushed := err == nil
+	if pushed {
+		head, err := repo.Head()
 		if err != nil {
-			return fmt.Errorf("failed to determine repository: %w", err)
+			return false, fmt.Errorf("failed to resolve published commit: %w", err)
+		}
+
+		record := publishRecord{Repository: repository, Branch: branch, Previous: previous.String(), Published: head.Hash().String()}
+		if err := recordPublish(record); err != nil {
+			return false, fmt.Errorf("failed to record publish: %w", err)
 		}
 	}
 
-	temporaryDirectory, err := os.MkdirTemp("", "kontrolplane-publish-directory-*")
-	if err != nil {
-		return fmt.Errorf("failed to create temporary directory: %w", err)
+	if cfg.PullRequestBranch != "" {
+		head, err := repo.Head()
+		if err != nil {
+			return false, fmt.Errorf("failed to resolve published commit: %w", err)
+		}
+
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return false, fmt.Errorf("failed to read commit: %w", err)
+		}
+
+		title, _, _ := strings.Cut(commit.Message, "\n")
+		if err := openPullRequest(cfg, repository, title); err != nil {
+			return false, err
+		}
 	}
-	defer os.RemoveAll(temporaryDirectory)
 
-	url := fmt.Sprintf("https://github.com/%s.git", repository)
+	return pushed, nil
+}
+
+// prepareWorktree clones the target branch into dir, replaces its content with
+// the source folder and stages the result.
+func prepareWorktree(cfg Config, repository, url string, auth transport.AuthMethod, dir, lfsStorage string, diag *diagnostics) (*git.Repository, []lfsObject, error) {
+	diag.phase("clone")
 
-	auth := &http.BasicAuth{
-		Username: "x-access-token",
-		Password: cfg.GithubToken,
+	// The size budget covers the history of the branch, which a shallow clone
+	// does not contain.
+	depth := 1
+	if cfg.SizeBudget != "" {
+		depth = 0
 	}
 
-	repo, err := cloneOrCreateBranch(url, cfg.Branch, temporaryDirectory, auth)
+	stop := spinner(msg(messageCloning, cfg.Branch))
+	repo, err := cloneOrCreateBranch(url, cfg.Branch, dir, auth, depth)
+	stop()
 	if err != nil {
-		return err
+		if checksGithubStatus(cfg) {
+			return nil, nil, incidentError(err)
+		}
+		return nil, nil, err
 	}
 
 	worktree, err := repo.Worktree()
 	if err != nil {
-		return fmt.Errorf("failed to get worktree: %w", err)
+		return nil, nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	if err := runHook(hookAfterClone, cfg.HookAfterClone, dir, cfg, ""); err != nil {
+		return nil, nil, err
+	}
+
+	diag.phase("copy")
+
+	contentDir := dir
+	if content := contentPath(cfg); content != "" {
+		contentDir = filepath.Join(dir, filepath.FromSlash(content))
+		if err := os.MkdirAll(contentDir, 0o755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create target directory: %w", err)
+		}
+	}
+
+	var helmIndex []byte
+	if cfg.Mode == modeHelm {
+		helmIndex, err = readHelmIndex(contentDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", helmIndexFile, err)
+		}
+	}
+
+	if cfg.Clean {
+		if err := cleanWorkingTree(contentDir, splitList(cfg.KeepFiles)); err != nil {
+			return nil, nil, fmt.Errorf("failed to clean working tree: %w", err)
+		}
+	}
+
+	if cfg.Manifest != "" {
+		folderConfig := cfg
+		folderConfig.Folder = sourceFolders(cfg)[0].Path
+
+		filters, err := sourceFilters(folderConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var kept pathFilter
+		if cfg.KeepFiles != "" {
+			kept = keepFilter(splitList(cfg.KeepFiles), contentDir)
+		}
+
+		entries, err := readManifest(cfg.Manifest)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+
+		if err := copyManifest(folderConfig.Folder, contentDir, entries, filters, kept); err != nil {
+			return nil, nil, fmt.Errorf("failed to copy manifest files: %w", err)
+		}
+	} else if err := copyFolders(cfg, contentDir); err != nil {
+		return nil, nil, fmt.Errorf("failed to copy directory: %w", err)
+	}
+
+	if err := runHook(hookAfterCopy, cfg.HookAfterCopy, dir, cfg, ""); err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.MtimeManifest != "" {
+		if err := writeMtimeManifest(cfg, dir, cfg.MtimeManifest); err != nil {
+			return nil, nil, fmt.Errorf("failed to write mtime manifest: %w", err)
+		}
+	}
+
+	if cfg.SBOM != "" {
+		created, err := sbomTime(cfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate SBOM: %w", err)
+		}
+
+		if err := writeSBOM(dir, cfg.SBOM, sbomPath(cfg), repository+"@"+cfg.Branch, created); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate SBOM: %w", err)
+		}
+	}
+
+	if cfg.Mode == modeHelm {
+		if err := mergeHelmIndex(cfg, contentDir, helmIndex); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if cfg.Version != "" {
+		if err := updateVersions(cfg, dir); err != nil {
+			return nil, nil, err
+		}
+
+		if err := writeAliases(cfg, dir); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// The custom domain belongs to the root of the site, not to a version.
+	if cfg.CNAME != "" {
+		if err := writeCNAME(filepath.Join(dir, filepath.FromSlash(cfg.TargetDir)), cfg.CNAME); err != nil {
+			return nil, nil, fmt.Errorf("failed to write %s: %w", cnameFile, err)
+		}
+	}
+
+	if cfg.NoJekyll {
+		if err := writeNoJekyll(dir); err != nil {
+			return nil, nil, fmt.Errorf("failed to write %s: %w", noJekyllFile, err)
+		}
+	}
+
+	if cfg.BadgePath != "" {
+		if err := writeBadge(dir, cfg.BadgePath, cfg.BadgeLabel, cfg.BadgeVersion, time.Now()); err != nil {
+			return nil, nil, fmt.Errorf("failed to write badge: %w", err)
+		}
+	}
+
+	if cfg.PolicyFile != "" {
+		if err := evaluatePolicies(cfg, dir, repository); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := scanContent(cfg, dir); err != nil {
+		return nil, nil, err
 	}
 
-	if err := cleanWorkingTree(temporaryDirectory); err != nil {
-		return fmt.Errorf("failed to clean working tree: %w", err)
+	if cfg.LicenseAllow != "" || cfg.LicenseDeny != "" {
+		if err := scanLicenses(dir, splitList(cfg.LicenseAllow), splitList(cfg.LicenseDeny), cfg.LicenseAction); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	lfsTrack := splitList(cfg.LFSTrack)
+	if cfg.LFSOversized {
+		oversized, err := findLargeFiles(dir, githubFileSizeLimit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check file sizes: %w", err)
+		}
+
+		if len(oversized) > 0 {
+			fmt.Println(msg(messageStoringOversized, len(oversized)))
+			lfsTrack = append(lfsTrack, lfsPatternsForPaths(oversized)...)
+			cfg.LFS = true
+		}
+	}
+
+	var lfsObjects []lfsObject
+	if cfg.LFS {
+		lfsObjects, err = convertToLFSPointers(dir, lfsStorage, lfsTrack)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert files to LFS pointers: %w", err)
+		}
 	}
 
-	if err := copyDirectory(cfg.Folder, temporaryDirectory); err != nil {
-		return fmt.Errorf("failed to copy directory: %w", err)
+	if err := checkFileSizes(dir); err != nil {
+		return nil, nil, err
 	}
 
+	diag.phase("stage")
+
 	if err := worktree.AddWithOptions(&git.AddOptions{All: true}); err != nil {
-		return fmt.Errorf("failed to stage changes: %w", err)
+		return nil, nil, fmt.Errorf("failed to stage changes: %w", err)
+	}
+
+	if err := preserveExecutableModes(repo); err != nil {
+		return nil, nil, fmt.Errorf("failed to preserve file modes: %w", err)
+	}
+
+	return repo, lfsObjects, nil
+}
+
+// resolveRemote determines the repository to publish to, along with its clone
+// URL and the credentials to access it. A configured remote URL is used as is,
+// with the configured credentials or otherwise those of the provider.
+func resolveRemote(cfg Config) (string, string, transport.AuthMethod, error) {
+	if cfg.RemoteURL != "" && cfg.Repository == "" {
+		cfg.Repository = cfg.RemoteURL
 	}
 
-	status, err := worktree.Status()
+	repository, err := resolveRepository(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to get status: %w", err)
+		return "", "", nil, fmt.Errorf("failed to determine repository: %w", err)
 	}
 
-	if status.IsClean() {
-		if cfg.SkipEmptyCommits {
-			fmt.Println("No changes to commit, skipping")
-			return nil
-		}
-		fmt.Println("No changes detected, but creating empty commit anyway")
+	provider, ok := providers[remoteProvider(cfg)]
+	if !ok {
+		return "", "", nil, fmt.Errorf("unknown provider '%s'", remoteProvider(cfg))
 	}
 
-	commit, err := worktree.Commit(cfg.CommitMessage, &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  cfg.CommitUser,
-			Email: cfg.CommitEmail,
-			When:  time.Now(),
-		},
-		AllowEmptyCommits: !cfg.SkipEmptyCommits,
-	})
+	url, auth, err := provider.remote(cfg, repository)
 	if err != nil {
-		return fmt.Errorf("failed to commit: %w", err)
+		return "", "", nil, err
+	}
+
+	if cfg.RemoteURL != "" {
+		url = cfg.RemoteURL
+		if cfg.RemoteUsername != "" || cfg.RemotePassword != "" {
+			auth = &http.BasicAuth{
+				Username: cfg.RemoteUsername,
+				Password: cfg.RemotePassword,
+			}
+		}
 	}
 
-	fmt.Printf("Created commit: %s\n", commit.String())
+	return repository, url, auth, nil
+}
+
+// resolveRepository returns the configured repository, falling back to the
+// repository the workflow or GitLab CI job runs in.
+func resolveRepository(cfg Config) (string, error) {
+	if cfg.Repository != "" {
+		return cfg.Repository, nil
+	}
+	if project := os.Getenv("CI_PROJECT_PATH"); project != "" && remoteProvider(cfg) == providerGitlab {
+		return project, nil
+	}
+	if cfg.GithubRepository != "" {
+		return cfg.GithubRepository, nil
+	}
+	return getCurrentRepository()
+}
+
+// checkExpectedHead fails when the cloned branch is not at the expected
+// commit, because another publish moved it.
+func checkExpectedHead(repo *git.Repository, branch string, expected plumbing.Hash) error {
+	head := plumbing.ZeroHash
+	if reference, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true); err == nil {
+		head = reference.Hash()
+	}
 
-	if err := repo.Push(&git.PushOptions{
-		RemoteName: "origin",
-		Auth:       auth,
-		Progress:   os.Stdout,
-	}); err != nil {
-		return fmt.Errorf("failed to push: %w", err)
+	if head != expected {
+		return fmt.Errorf("%w: branch '%s' is at %s, expected %s", errUnexpectedHead, branch, head, expected)
 	}
 
 	return nil
 }
 
+// hasHistory reports whether the tip of the cloned branch has parents.
+func hasHistory(repo *git.Repository) bool {
+	head, err := repo.Head()
+	if err != nil {
+		return false
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	return err == nil && commit.NumParents() > 0
+}
+
+// errUnexpectedHead is returned when the branch is not at the expected head
+// commit.
+var errUnexpectedHead = errors.New("branch moved")
+
+// errSelfPublish is returned when publishing would replace the branch the
+// workflow is running from.
+var errSelfPublish = errors.New("refusing to publish to the branch the workflow runs from")
+
+// isWorkflowBranch reports whether branch of repository is the branch the
+// workflow is running from.
+func isWorkflowBranch(repository, branch string) bool {
+	ref := os.Getenv("GITHUB_REF")
+	return strings.EqualFold(repository, os.Getenv("GITHUB_REPOSITORY")) &&
+		ref == plumbing.NewBranchReferenceName(branch).String()
+}
+
 func getCurrentRepository() (string, error) {
 	repo := os.Getenv("GITHUB_REPOSITORY")
 	if repo == "" {
@@ -154,36 +1459,33 @@ func getCurrentRepository() (string, error) {
 	return repo, nil
 }
 
-func cloneOrCreateBranch(gitURL, branch string, targetDir string, auth *http.BasicAuth) (*git.Repository, error) {
+func cloneOrCreateBranch(gitURL, branch string, targetDir string, auth transport.AuthMethod, depth int) (*git.Repository, error) {
 	branchReference := plumbing.NewBranchReferenceName(branch)
 	repo, err := git.PlainClone(targetDir, false, &git.CloneOptions{
 		URL:           gitURL,
 		Auth:          auth,
 		ReferenceName: branchReference,
 		SingleBranch:  true,
-		Depth:         1,
+		Depth:         depth,
 	})
 	if err == nil {
 		return repo, nil
 	}
 
-	fmt.Printf("Branch '%s' doesn't exist, creating new orphan branch\n", branch)
+	// Only a branch that does not exist is created; any other failure must
+	// not lead to an orphan replacing the branch.
+	if !isMissingBranch(err) {
+		return nil, fmt.Errorf("failed to clone branch '%s': %w", branch, err)
+	}
+
+	fmt.Println(msg(messageCreatingBranch, branch))
 
 	repo, err = git.PlainInit(targetDir, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to init repository: %w", err)
 	}
 
-	worktree, err := repo.Worktree()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get worktree: %w", err)
-	}
-
-	err = worktree.Checkout(&git.CheckoutOptions{
-		Branch: branchReference,
-		Create: true,
-	})
-	if err != nil {
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branchReference)); err != nil {
 		return nil, fmt.Errorf("failed to create branch: %w", err)
 	}
 
@@ -198,19 +1500,51 @@ func cloneOrCreateBranch(gitURL, branch string, targetDir string, auth *http.Bas
 	return repo, nil
 }
 
-func cleanWorkingTree(dir string) error {
-	entries, err := os.ReadDir(dir)
+// isMissingBranch reports whether a clone failed because the branch, or any
+// branch at all, does not exist on the remote.
+func isMissingBranch(err error) bool {
+	var noMatch git.NoMatchingRefSpecError
+	return errors.Is(err, plumbing.ErrReferenceNotFound) ||
+		errors.Is(err, transport.ErrEmptyRemoteRepository) ||
+		errors.As(err, &noMatch)
+}
+
+// cleanWorkingTree removes everything in dir except the .git folder and the
+// paths matching the keep patterns, which follow the .gitignore syntax.
+func cleanWorkingTree(dir string, keep []string) error {
+	var removed pathFilter
+	if len(keep) > 0 {
+		removed = includeFilter(keep)
+	}
+
+	return cleanDirectory(dir, nil, removed)
+}
+
+// cleanDirectory removes the entries of the directory at path below root that
+// the filter selects for removal, descending into directories that contain
+// kept paths. A nil filter removes every entry.
+func cleanDirectory(root string, path []string, removed pathFilter) error {
+	entries, err := os.ReadDir(filepath.Join(root, filepath.Join(path...)))
 	if err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
-		if entry.Name() == ".git" {
+		if len(path) == 0 && entry.Name() == ".git" {
+			continue
+		}
+
+		entryPath := append(append([]string{}, path...), entry.Name())
+		if removed != nil && !removed(entryPath, entry.IsDir()) {
+			if entry.IsDir() {
+				if err := cleanDirectory(root, entryPath, removed); err != nil {
+					return err
+				}
+			}
 			continue
 		}
 
-		path := filepath.Join(dir, entry.Name())
-		if err := os.RemoveAll(path); err != nil {
+		if err := os.RemoveAll(filepath.Join(root, filepath.Join(entryPath...))); err != nil {
 			return err
 		}
 	}
@@ -218,7 +1552,7 @@ func cleanWorkingTree(dir string) error {
 	return nil
 }
 
-func copyDirectory(source, destination string) error {
+func copyDirectory(source, destination string, filters []pathFilter) error {
 	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -233,7 +1567,14 @@ func copyDirectory(source, destination string) error {
 			return err
 		}
 
-		targetPath := filepath.Join(source, relativePath)
+		if relativePath != "." && isFiltered(filters, splitPath(relativePath), info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		targetPath := filepath.Join(destination, relativePath)
 
 		if info.IsDir() {
 			return os.MkdirAll(targetPath, info.Mode())
@@ -265,5 +1606,9 @@ func copyFile(source, destination string) error {
 		return err
 	}
 
-	return os.Chmod(destination, sourceInfo.Mode())
+	if err := os.Chmod(destination, sourceInfo.Mode()); err != nil {
+		return err
+	}
+
+	return os.Chtimes(destination, sourceInfo.ModTime(), sourceInfo.ModTime())
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// manifestEntry describes a single file listed in a manifest, relative to the
+// source folder, and the path it should be published at on the branch.
+type manifestEntry struct {
+	Source string
+	Target string
+}
+
+// readManifest parses a manifest file. Every non-empty line that does not start
+// with '#' lists a path relative to the source folder, optionally followed by
+// '=>' and the target path on the branch.
+func readManifest(path string) ([]manifestEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []manifestEntry
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		source, target, found := strings.Cut(line, "=>")
+		source = strings.TrimSpace(source)
+		target = strings.TrimSpace(target)
+		if !found {
+			target = source
+		}
+
+		if source == "" || target == "" {
+			return nil, fmt.Errorf("line %d: empty path", lineNumber)
+		}
+
+		if !filepath.IsLocal(filepath.FromSlash(source)) {
+			return nil, fmt.Errorf("line %d: path '%s' must be relative and stay within the folder", lineNumber, source)
+		}
+
+		if !isBranchPath(target) {
+			return nil, fmt.Errorf("line %d: target '%s' must be a relative path inside the branch and outside .git", lineNumber, target)
+		}
+
+		entries = append(entries, manifestEntry{
+			Source: filepath.FromSlash(source),
+			Target: filepath.FromSlash(target),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// copyManifest copies only the files listed in the manifest from source into
+// destination, creating parent directories as required. The filters apply to
+// the source paths, kept to the targets on the branch.
+func copyManifest(source, destination string, entries []manifestEntry, filters []pathFilter, kept pathFilter) error {
+	for _, entry := range entries {
+		if isFiltered(filters, splitPath(entry.Source), false) {
+			continue
+		}
+
+		if kept != nil && kept(splitPath(entry.Target), false) {
+			continue
+		}
+
+		sourcePath := filepath.Join(source, entry.Source)
+
+		info, err := os.Stat(sourcePath)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return fmt.Errorf("manifest entry '%s' is a directory", entry.Source)
+		}
+
+		targetPath := filepath.Join(destination, entry.Target)
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+			return err
+		}
+
+		if err := copyFile(sourcePath, targetPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var conventionalCommitExpression = regexp.MustCompile(`^(build|chore|ci|docs|feat|fix|perf|refactor|revert|style|test)(\([^()]+\))?!?: \S`)
+
+// commitMessage returns the message for the publish commit, with {branch}
+// and the run context placeholders expanded, dated like the commit. A message
+// read from the commit message file is used verbatim.
+func commitMessage(cfg Config) (string, error) {
+	when, err := commitTime(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	message := expandTemplate(strings.ReplaceAll(cfg.CommitMessage, "{branch}", cfg.Branch), when)
+
+	if cfg.CommitMessageFile != "" {
+		data, err := os.ReadFile(cfg.CommitMessageFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read commit message file: %w", err)
+		}
+
+		message = strings.TrimRight(string(data), "\n")
+		if strings.TrimSpace(message) == "" {
+			return "", fmt.Errorf("commit message file '%s' is empty", cfg.CommitMessageFile)
+		}
+	}
+
+	if err := lintCommitMessage(cfg, message); err != nil {
+		return "", err
+	}
+
+	return message, nil
+}
+
+// commitTrailers returns the trailers appended to the publish commit message.
+func commitTrailers(cfg Config) ([]string, error) {
+	trailers := []string{publishTrailer}
+
+	if cfg.SourceTrailers {
+		if sha := os.Getenv("GITHUB_SHA"); sha != "" {
+			trailers = append(trailers, "Source-Commit: "+sha)
+		}
+		// The run differs between otherwise identical runs, so it is left out
+		// of reproducible commits.
+		if runID := os.Getenv("GITHUB_RUN_ID"); runID != "" && !reproducibleTime(cfg) {
+			trailers = append(trailers, fmt.Sprintf("Source-Run: %s/%s/actions/runs/%s", githubServerURL(), os.Getenv("GITHUB_REPOSITORY"), runID))
+		}
+	}
+
+	if cfg.CoAuthorFromSource {
+		commit, err := sourceCommit(sourceFolders(cfg)[0].Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source commit: %w", err)
+		}
+
+		// Crediting the publishing identity itself adds nothing.
+		if !strings.EqualFold(commit.Author.Email, cfg.CommitEmail) {
+			trailers = append(trailers, fmt.Sprintf("Co-authored-by: %s <%s>", commit.Author.Name, commit.Author.Email))
+		}
+	}
+
+	if cfg.SignOff {
+		trailers = append(trailers, fmt.Sprintf("Signed-off-by: %s <%s>", cfg.CommitUser, cfg.CommitEmail))
+	}
+
+	return trailers, nil
+}
+
+// lintCommitMessage validates the subject of the commit message against the
+// conventional commit format and the custom pattern, when configured.
+func lintCommitMessage(cfg Config, message string) error {
+	subject, _, _ := strings.Cut(message, "\n")
+
+	if cfg.CommitMessageLint == "conventional" && !conventionalCommitExpression.MatchString(subject) {
+		return fmt.Errorf("commit message '%s' is not a conventional commit, expected e.g. 'chore: update docs'", subject)
+	}
+
+	if cfg.CommitMessagePattern != "" {
+		pattern, err := regexp.Compile(cfg.CommitMessagePattern)
+		if err != nil {
+			return fmt.Errorf("invalid commit message pattern: %w", err)
+		}
+
+		if !pattern.MatchString(message) {
+			return fmt.Errorf("commit message '%s' does not match the pattern '%s'", subject, cfg.CommitMessagePattern)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Keys of the user-facing messages in the catalog.
+const (
+	messageError               = "error"
+	messageHint                = "hint"
+	messageLoadConfigFailed    = "load_config_failed"
+	messageConfigError         = "config_error"
+	messagePublished           = "published"
+	messageDryRunComplete      = "dry_run_complete"
+	messageNoChanges           = "no_changes"
+	messageEmptyCommit         = "empty_commit"
+	messageCreatedCommit       = "created_commit"
+	messageCreatingBranch      = "creating_branch"
+	messageCloning             = "cloning"
+	messagePushing             = "pushing"
+	messageConfirmPublish      = "confirm_publish"
+	messageConfirmRepair       = "confirm_repair"
+	messageConfirmOptions      = "confirm_options"
+	messageConfirmAccept       = "confirm_accept"
+	messagePublishingTarget    = "publishing_target"
+	messageSkippingTarget      = "skipping_target"
+	messageSummary             = "summary"
+	messageSummarySucceeded    = "summary_succeeded"
+	messageSummaryFailed       = "summary_failed"
+	messageHintProtected       = "hint_protected"
+	messageHintAuthentication  = "hint_authentication"
+	messageHintAuthorization   = "hint_authorization"
+	messageHintNotFound        = "hint_not_found"
+	messageHintNonFastForward  = "hint_non_fast_forward"
+	messageHintSelfPublish     = "hint_self_publish"
+	messageRebasing            = "rebasing"
+	messageRulesetPullRequest  = "ruleset_pull_request"
+	messageResuming            = "resuming"
+	messageSquashing           = "squashing"
+	messageReplacingHistory    = "replacing_history"
+	messageDryRunChanges       = "dry_run_changes"
+	messageDryRunMessage       = "dry_run_message"
+	messageSnapshotBranch      = "snapshot_branch"
+	messageStoringOversized    = "storing_oversized"
+	messageSafetyExclude       = "safety_exclude"
+	messagePagesUnchanged      = "pages_unchanged"
+	messagePagesEnabled        = "pages_enabled"
+	messagePagesBuildRequested = "pages_build_requested"
+	messagePagesBuildWaiting   = "pages_build_waiting"
+	messagePagesDeployed       = "pages_deployed"
+	messageUploadingAsset      = "uploading_asset"
+	messageReleasePublished    = "release_published"
+	messageReplacingAsset      = "replacing_asset"
+	messageReleaseUpdated      = "release_updated"
+	messageNoPruneMatches      = "no_prune_matches"
+	messageLastPublished       = "last_published"
+	messagePruned              = "pruned"
+	messageNothingToCleanUp    = "nothing_to_clean_up"
+	messageDeletingBranch      = "deleting_branch"
+	messagePullRequestUpdated  = "pull_request_updated"
+	messagePullRequestOpened   = "pull_request_opened"
+	messageBranchUpToDate      = "branch_up_to_date"
+	messageRemovingVersion     = "removing_version"
+	messageRemovingAlias       = "removing_alias"
+	messageAddingChart         = "adding_chart"
+
+	messageJobFailed               = "job_failed"
+	messageJobPublished            = "job_published"
+	messageWaitingForApproval      = "waiting_for_approval"
+	messageApproved                = "approved"
+	messageSizeBudgetExceeded      = "size_budget_exceeded"
+	messageBranchSize              = "branch_size"
+	messageCheckPassed             = "check_passed"
+	messageCheckFailed             = "check_failed"
+	messageBranchHealthy           = "branch_healthy"
+	messageCheckReachable          = "check_reachable"
+	messageCheckTipInvalid         = "check_tip_invalid"
+	messageCheckTip                = "check_tip"
+	messageCheckTreeInvalid        = "check_tree_invalid"
+	messageCheckTree               = "check_tree"
+	messageCheckPublished          = "check_published"
+	messageCheckSBOMPresent        = "check_sbom_present"
+	messageCheckSBOMReadable       = "check_sbom_readable"
+	messageCheckSBOMParses         = "check_sbom_parses"
+	messageCheckSBOMMissing        = "check_sbom_missing"
+	messageCheckSBOMMismatch       = "check_sbom_mismatch"
+	messageCheckSBOMUnlisted       = "check_sbom_unlisted"
+	messageCheckSBOM               = "check_sbom"
+	messageCommentPublished        = "comment_published"
+	messageCommentPreview          = "comment_preview"
+	messageCommentChanges          = "comment_changes"
+	messageDeploymentStatusFailed  = "deployment_status_failed"
+	messageDiagnosticsWritten      = "diagnostics_written"
+	messageNoDrift                 = "no_drift"
+	messageDriftTitle              = "drift_title"
+	messageDrift                   = "drift"
+	messageDriftBackup             = "drift_backup"
+	messageDriftRepaired           = "drift_repaired"
+	messageRunningHook             = "running_hook"
+	messageUploadedLFS             = "uploaded_lfs"
+	messageLicenseNotAllowed       = "license_not_allowed"
+	messageRemoveStateFailed       = "remove_state_failed"
+	messageDiagnosticsFailed       = "diagnostics_failed"
+	messageUnknownInput            = "unknown_input"
+	messageRestoredMtimes          = "restored_mtimes"
+	messageReconcilingEvery        = "reconciling_every"
+	messageListResourcesFailed     = "list_resources_failed"
+	messageReconciling             = "reconciling"
+	messageReconcileFailed         = "reconcile_failed"
+	messageStatusUpdateFailed      = "status_update_failed"
+	messageRemoveFailed            = "remove_failed"
+	messageRevertFailed            = "revert_failed"
+	messageReverting               = "reverting"
+	messagePushRetry               = "push_retry"
+	messageRulesetsFailed          = "rulesets_failed"
+	messageScanning                = "scanning"
+	messageRunningJob              = "running_job"
+	messageJobRepairFailed         = "job_repair_failed"
+	messageScheduleNeverFires      = "schedule_never_fires"
+	messageListening               = "listening"
+	messageLargeFile               = "large_file"
+	messageSummaryTitle            = "summary_title"
+	messageSummaryUpToDate         = "summary_up_to_date"
+	messageSummaryCommit           = "summary_commit"
+	messageSummaryAdded            = "summary_added"
+	messageSummaryModified         = "summary_modified"
+	messageSummaryDeleted          = "summary_deleted"
+	messageSummarySize             = "summary_size"
+	messagePushingTag              = "pushing_tag"
+	messageLoadStateFailed         = "load_state_failed"
+	messageResolveRepositoryFailed = "resolve_repository_failed"
+	messageSetOutputFailed         = "set_output_failed"
+	messageRefreshingToken         = "refreshing_token"
+	messageURLManifest             = "url_manifest"
+	messageVerified                = "verified"
+	messageVerifyMissing           = "verify_missing"
+	messageVerifyDiffers           = "verify_differs"
+	messageVerifyOnlyOnBranch      = "verify_only_on_branch"
+)
+
+const defaultLocale = "en"
+
+// catalog holds the user-facing messages per locale. Messages missing from a
+// locale fall back to English.
+var catalog = map[string]map[string]string{
+	"en": {
+		messageError:               "Error:",
+		messageHint:                "Hint: %s",
+		messageLoadConfigFailed:    "Failed to load configuration: %v",
+		messageConfigError:         "Configuration error: %v",
+		messagePublished:           "Successfully published directory to branch",
+		messageDryRunComplete:      "Dry run complete, nothing was committed or pushed",
+		messageNoChanges:           "No changes to commit, skipping",
+		messageEmptyCommit:         "No changes detected, but creating empty commit anyway",
+		messageCreatedCommit:       "Created commit: %s",
+		messageCreatingBranch:      "Branch '%s' doesn't exist, creating new orphan branch",
+		messageCloning:             "Cloning branch '%s'",
+		messagePushing:             "Pushing branch '%s'",
+		messageConfirmPublish:      "Rewrite the history of branch '%s' in %s with these changes?\n%s",
+		messageConfirmRepair:       "Force-push branch '%s' back to %s, discarding %d commits?",
+		messageConfirmOptions:      "[y/N]",
+		messageConfirmAccept:       "y,yes",
+		messagePublishingTarget:    "Publishing to %s",
+		messageSkippingTarget:      "Skipping %s, already published by an earlier run",
+		messageSummary:             "Publish summary:",
+		messageSummarySucceeded:    "  succeeded: %s",
+		messageSummaryFailed:       "  failed:    %s",
+		messageHintProtected:       "The branch is protected or covered by a ruleset. Allow the token's identity to bypass the protection, or publish to an unprotected branch.",
+		messageHintAuthentication:  "The remote rejected the credentials. Make sure github_token is set to a valid token for the target repository.",
+		messageHintAuthorization:   "The token is not allowed to push. Grant the workflow 'contents: write' permission, or use a personal access token, GitHub App or deploy key with write access to the target repository.",
+		messageHintNotFound:        "The repository could not be found. Check the repository input for typos, and make sure the token can access it; private repositories are reported as not found when access is missing.",
+		messageHintNonFastForward:  "The remote branch moved while publishing, most likely because another workflow published concurrently. Re-run the job, or serialise publishes with a concurrency group.",
+		messageHintSelfPublish:     "Publishing replaces the content of the branch, including the workflow that is running. Publish to another branch, or set allow_self_publish if this is intended.",
+		messageRebasing:            "Branch '%s' moved during the publish, publishing onto its new tip (%d/%d)",
+		messageRulesetPullRequest:  "Branch '%s' is covered by rulesets that reject direct pushes: %s; publishing through a pull request from '%s'",
+		messageResuming:            "Resuming %s publish from the %s phase",
+		messageSquashing:           "Squashing history: %s",
+		messageReplacingHistory:    "Replacing the history of the branch with a single commit",
+		messageDryRunChanges:       "Dry run, the publish to %s would change:\n%s",
+		messageDryRunMessage:       "With commit message:\n%s",
+		messageSnapshotBranch:      "Publishing snapshot branch '%s'",
+		messageStoringOversized:    "Storing %d files larger than 100 MB with Git LFS",
+		messageSafetyExclude:       "Skipping '%s' (built-in safety exclude)",
+		messagePagesUnchanged:      "GitHub Pages already publishes from '%s' at %s, leaving it unchanged",
+		messagePagesEnabled:        "Enabled GitHub Pages publishing from '%s' at %s",
+		messagePagesBuildRequested: "Requested GitHub Pages build",
+		messagePagesBuildWaiting:   "Waiting up to %s for the GitHub Pages build",
+		messagePagesDeployed:       "GitHub Pages deployed %s",
+		messageUploadingAsset:      "Uploading release asset '%s'",
+		messageReleasePublished:    "Published release %s",
+		messageReplacingAsset:      "Replacing release asset '%s'",
+		messageReleaseUpdated:      "Updated release %s",
+		messageNoPruneMatches:      "No branches match the prune patterns",
+		messageLastPublished:       "Branch '%s' was last published %s",
+		messagePruned:              "Pruned %d of %d matching branches older than %s",
+		messageNothingToCleanUp:    "Branch '%s' does not exist, nothing to clean up",
+		messageDeletingBranch:      "Deleting branch '%s'",
+		messagePullRequestUpdated:  "Updated pull request %s",
+		messagePullRequestOpened:   "Opened pull request %s",
+		messageBranchUpToDate:      "Branch '%s' is up to date",
+		messageRemovingVersion:     "Removing version '%s'",
+		messageRemovingAlias:       "Removing alias '%s' of version '%s'",
+		messageAddingChart:         "Adding chart %s %s to %s",
+
+		messageJobFailed:               "Job '%s' (%s, run %s) failed: %v",
+		messageJobPublished:            "Job '%s' (%s, run %s) published to %s",
+		messageWaitingForApproval:      "Waiting up to %s for approval on #%d",
+		messageApproved:                "Publish approved by %s",
+		messageSizeBudgetExceeded:      "Branch size budget exceeded",
+		messageBranchSize:              "Estimated branch size: %s (budget %s)",
+		messageCheckPassed:             "ok:     %s",
+		messageCheckFailed:             "failed: %s",
+		messageBranchHealthy:           "Branch is healthy",
+		messageCheckReachable:          "branch '%s' is reachable",
+		messageCheckTipInvalid:         "tip %s does not parse: %v",
+		messageCheckTip:                "tip %s parses",
+		messageCheckTreeInvalid:        "tree of %s does not parse: %v",
+		messageCheckTree:               "tree %s with %d files parses",
+		messageCheckPublished:          "tip was created by publish-directory",
+		messageCheckSBOMPresent:        "SBOM '%s' is present",
+		messageCheckSBOMReadable:       "SBOM '%s' is readable: %v",
+		messageCheckSBOMParses:         "SBOM '%s' parses: %v",
+		messageCheckSBOMMissing:        "'%s' listed in the SBOM is missing from the tree",
+		messageCheckSBOMMismatch:       "'%s' does not match the SBOM",
+		messageCheckSBOMUnlisted:       "'%s' in the tree is not listed in the SBOM",
+		messageCheckSBOM:               "tree matches the %d files in SBOM '%s'",
+		messageCommentPublished:        "**publish-directory** published this pull request to [`%s`](%s) as `%s`.",
+		messageCommentPreview:          "Preview: %s",
+		messageCommentChanges:          "%d added, %d modified, %d deleted",
+		messageDeploymentStatusFailed:  "Failed to mark deployment as failed: %v",
+		messageDiagnosticsWritten:      "Wrote diagnostics bundle to %s",
+		messageNoDrift:                 "Branch '%s' matches the last publish %s",
+		messageDriftTitle:              "Branch drift",
+		messageDrift:                   "Branch '%s' has %d commits made outside of publish-directory since %s",
+		messageDriftBackup:             "Preserved foreign commits on branch '%s'",
+		messageDriftRepaired:           "Reset branch '%s' to the last publish %s",
+		messageRunningHook:             "Running %s hook",
+		messageUploadedLFS:             "Uploaded %d LFS objects",
+		messageLicenseNotAllowed:       "'%s' is not an allowed license",
+		messageRemoveStateFailed:       "Failed to remove state: %v",
+		messageDiagnosticsFailed:       "Failed to write diagnostics bundle: %v",
+		messageUnknownInput:            "Unknown input '%s' is ignored",
+		messageRestoredMtimes:          "Restored modification times for %d files",
+		messageReconcilingEvery:        "Reconciling %s every %s",
+		messageListResourcesFailed:     "Failed to list %s: %v",
+		messageReconciling:             "Reconciling %s",
+		messageReconcileFailed:         "Failed to reconcile %s: %v",
+		messageStatusUpdateFailed:      "Failed to update status of %s: %v",
+		messageRemoveFailed:            "Failed to remove '%s': %v",
+		messageRevertFailed:            "Failed to revert %s: %v",
+		messageReverting:               "Reverting %s to %s",
+		messagePushRetry:               "Push failed: %v, retrying in %s (%d/%d)",
+		messageRulesetsFailed:          "Failed to query rulesets of '%s': %v",
+		messageScanning:                "Scanning content for malware",
+		messageRunningJob:              "Running job '%s'",
+		messageJobRepairFailed:         "Failed to repair drift of job '%s': %v",
+		messageScheduleNeverFires:      "Schedule of job '%s' never fires",
+		messageListening:               "Listening on %s with %d jobs",
+		messageLargeFile:               "Warning: '%s' is larger than 50 MB, consider storing it with Git LFS",
+		messageSummaryTitle:            "### Published `%s` to %s",
+		messageSummaryUpToDate:         "The branch was already up to date.",
+		messageSummaryCommit:           "Commit",
+		messageSummaryAdded:            "Added",
+		messageSummaryModified:         "Modified",
+		messageSummaryDeleted:          "Deleted",
+		messageSummarySize:             "Total size",
+		messagePushingTag:              "Pushing tag '%s'",
+		messageLoadStateFailed:         "Failed to load state: %v",
+		messageResolveRepositoryFailed: "Failed to determine repository for %s: %v",
+		messageSetOutputFailed:         "Failed to set output %s: %v",
+		messageRefreshingToken:         "Credentials were rejected, refreshing the token",
+		messageURLManifest:             "Wrote URL manifest with %d files to %s",
+		messageVerified:                "Branch '%s' at %s matches folder '%s' (%d files)",
+		messageVerifyMissing:           "missing on branch: %s",
+		messageVerifyDiffers:           "differs:           %s",
+		messageVerifyOnlyOnBranch:      "only on branch:    %s",
+	},
+	"nl": {
+		messageError:               "Fout:",
+		messageHint:                "Tip: %s",
+		messageLoadConfigFailed:    "Configuratie laden mislukt: %v",
+		messageConfigError:         "Configuratiefout: %v",
+		messagePublished:           "Map succesvol gepubliceerd naar branch",
+		messageDryRunComplete:      "Proefdraaien voltooid, er is niets gecommit of gepusht",
+		messageNoChanges:           "Geen wijzigingen om te committen, overgeslagen",
+		messageEmptyCommit:         "Geen wijzigingen gevonden, toch een lege commit aangemaakt",
+		messageCreatedCommit:       "Commit aangemaakt: %s",
+		messageCreatingBranch:      "Branch '%s' bestaat niet, nieuwe orphan branch wordt aangemaakt",
+		messageCloning:             "Branch '%s' klonen",
+		messagePushing:             "Branch '%s' pushen",
+		messageConfirmPublish:      "De geschiedenis van branch '%s' in %s herschrijven met deze wijzigingen?\n%s",
+		messageConfirmRepair:       "Branch '%s' geforceerd terugzetten naar %s, waarbij %d commits verloren gaan?",
+		messageConfirmOptions:      "[j/N]",
+		messageConfirmAccept:       "j,ja,y,yes",
+		messagePublishingTarget:    "Publiceren naar %s",
+		messageSkippingTarget:      "%s overgeslagen, al gepubliceerd door een eerdere run",
+		messageSummary:             "Samenvatting:",
+		messageSummarySucceeded:    "  geslaagd: %s",
+		messageSummaryFailed:       "  mislukt:  %s",
+		messageHintProtected:       "De branch is beschermd of valt onder een ruleset. Sta de identiteit van het token toe de bescherming te omzeilen, of publiceer naar een onbeschermde branch.",
+		messageHintAuthentication:  "De remote weigerde de inloggegevens. Zorg dat github_token een geldig token voor de doelrepository bevat.",
+		messageHintAuthorization:   "Het token mag niet pushen. Geef de workflow 'contents: write' rechten, of gebruik een personal access token, GitHub App of deploy key met schrijfrechten op de doelrepository.",
+		messageHintNotFound:        "De repository is niet gevonden. Controleer de repository input op typfouten en zorg dat het token er toegang toe heeft; private repositories worden als niet gevonden gemeld zonder toegang.",
+		messageHintNonFastForward:  "De remote branch is tijdens het publiceren veranderd, waarschijnlijk door een andere workflow. Draai de job opnieuw, of publiceer na elkaar met een concurrency group.",
+		messageHintSelfPublish:     "Publiceren vervangt de inhoud van de branch, inclusief de workflow die nu draait. Publiceer naar een andere branch, of zet allow_self_publish als dit de bedoeling is.",
+		messageRebasing:            "Branch '%s' is tijdens het publiceren veranderd, publiceren op de nieuwe tip (%d/%d)",
+		messageRulesetPullRequest:  "Branch '%s' valt onder rulesets die directe pushes weigeren: %s; publiceren via een pull request vanaf '%s'",
+		messageResuming:            "Publicatie naar %s hervat vanaf de fase %s",
+		messageSquashing:           "Geschiedenis samenvoegen: %s",
+		messageReplacingHistory:    "De geschiedenis van de branch wordt vervangen door één commit",
+		messageDryRunChanges:       "Proefdraaien, de publicatie naar %s zou wijzigen:\n%s",
+		messageDryRunMessage:       "Met commitbericht:\n%s",
+		messageSnapshotBranch:      "Snapshot-branch '%s' publiceren",
+		messageStoringOversized:    "%d bestanden groter dan 100 MB worden met Git LFS opgeslagen",
+		messageSafetyExclude:       "'%s' overgeslagen (ingebouwde veiligheidsuitsluiting)",
+		messagePagesUnchanged:      "GitHub Pages publiceert al vanaf '%s' op %s, ongewijzigd gelaten",
+		messagePagesEnabled:        "GitHub Pages publiceert nu vanaf '%s' op %s",
+		messagePagesBuildRequested: "GitHub Pages-build aangevraagd",
+		messagePagesBuildWaiting:   "Maximaal %s wachten op de GitHub Pages-build",
+		messagePagesDeployed:       "GitHub Pages heeft %s uitgerold",
+		messageUploadingAsset:      "Release-asset '%s' uploaden",
+		messageReleasePublished:    "Release %s gepubliceerd",
+		messageReplacingAsset:      "Release-asset '%s' vervangen",
+		messageReleaseUpdated:      "Release %s bijgewerkt",
+		messageNoPruneMatches:      "Geen branches komen overeen met de prune-patronen",
+		messageLastPublished:       "Branch '%s' is voor het laatst gepubliceerd op %s",
+		messagePruned:              "%d van %d overeenkomende branches ouder dan %s opgeruimd",
+		messageNothingToCleanUp:    "Branch '%s' bestaat niet, niets op te ruimen",
+		messageDeletingBranch:      "Branch '%s' verwijderen",
+		messagePullRequestUpdated:  "Pull request %s bijgewerkt",
+		messagePullRequestOpened:   "Pull request %s geopend",
+		messageBranchUpToDate:      "Branch '%s' is al bijgewerkt",
+		messageRemovingVersion:     "Versie '%s' verwijderen",
+		messageRemovingAlias:       "Alias '%s' van versie '%s' verwijderen",
+		messageAddingChart:         "Chart %s %s toevoegen aan %s",
+
+		messageJobFailed:               "Job '%s' (%s, run %s) mislukt: %v",
+		messageJobPublished:            "Job '%s' (%s, run %s) gepubliceerd naar %s",
+		messageWaitingForApproval:      "Maximaal %s wachten op goedkeuring in #%d",
+		messageApproved:                "Publicatie goedgekeurd door %s",
+		messageSizeBudgetExceeded:      "Groottebudget van de branch overschreden",
+		messageBranchSize:              "Geschatte grootte van de branch: %s (budget %s)",
+		messageCheckPassed:             "ok:      %s",
+		messageCheckFailed:             "mislukt: %s",
+		messageBranchHealthy:           "Branch is gezond",
+		messageCheckReachable:          "branch '%s' is bereikbaar",
+		messageCheckTipInvalid:         "tip %s is niet te lezen: %v",
+		messageCheckTip:                "tip %s is leesbaar",
+		messageCheckTreeInvalid:        "tree van %s is niet te lezen: %v",
+		messageCheckTree:               "tree %s met %d bestanden is leesbaar",
+		messageCheckPublished:          "tip is aangemaakt door publish-directory",
+		messageCheckSBOMPresent:        "SBOM '%s' is aanwezig",
+		messageCheckSBOMReadable:       "SBOM '%s' is leesbaar: %v",
+		messageCheckSBOMParses:         "SBOM '%s' is te verwerken: %v",
+		messageCheckSBOMMissing:        "'%s' staat in de SBOM maar ontbreekt in de tree",
+		messageCheckSBOMMismatch:       "'%s' komt niet overeen met de SBOM",
+		messageCheckSBOMUnlisted:       "'%s' in de tree staat niet in de SBOM",
+		messageCheckSBOM:               "tree komt overeen met de %d bestanden in SBOM '%s'",
+		messageCommentPublished:        "**publish-directory** heeft deze pull request gepubliceerd naar [`%s`](%s) als `%s`.",
+		messageCommentPreview:          "Voorbeeld: %s",
+		messageCommentChanges:          "%d toegevoegd, %d gewijzigd, %d verwijderd",
+		messageDeploymentStatusFailed:  "Deployment als mislukt markeren mislukt: %v",
+		messageDiagnosticsWritten:      "Diagnosebundel geschreven naar %s",
+		messageNoDrift:                 "Branch '%s' komt overeen met de laatste publicatie %s",
+		messageDriftTitle:              "Branch-afwijking",
+		messageDrift:                   "Branch '%s' heeft %d commits buiten publish-directory om sinds %s",
+		messageDriftBackup:             "Vreemde commits bewaard op branch '%s'",
+		messageDriftRepaired:           "Branch '%s' teruggezet naar de laatste publicatie %s",
+		messageRunningHook:             "Hook %s uitvoeren",
+		messageUploadedLFS:             "%d LFS-objecten geüpload",
+		messageLicenseNotAllowed:       "'%s' is geen toegestane licentie",
+		messageRemoveStateFailed:       "Status verwijderen mislukt: %v",
+		messageDiagnosticsFailed:       "Diagnosebundel schrijven mislukt: %v",
+		messageUnknownInput:            "Onbekende input '%s' wordt genegeerd",
+		messageRestoredMtimes:          "Wijzigingstijden van %d bestanden hersteld",
+		messageReconcilingEvery:        "%s elke %s reconciliëren",
+		messageListResourcesFailed:     "%s ophalen mislukt: %v",
+		messageReconciling:             "%s reconciliëren",
+		messageReconcileFailed:         "%s reconciliëren mislukt: %v",
+		messageStatusUpdateFailed:      "Status van %s bijwerken mislukt: %v",
+		messageRemoveFailed:            "'%s' verwijderen mislukt: %v",
+		messageRevertFailed:            "%s terugdraaien mislukt: %v",
+		messageReverting:               "%s terugzetten naar %s",
+		messagePushRetry:               "Pushen mislukt: %v, opnieuw over %s (%d/%d)",
+		messageRulesetsFailed:          "Rulesets van '%s' opvragen mislukt: %v",
+		messageScanning:                "Inhoud scannen op malware",
+		messageRunningJob:              "Job '%s' uitvoeren",
+		messageJobRepairFailed:         "Afwijking van job '%s' herstellen mislukt: %v",
+		messageScheduleNeverFires:      "Schema van job '%s' gaat nooit af",
+		messageListening:               "Luisteren op %s met %d jobs",
+		messageLargeFile:               "Waarschuwing: '%s' is groter dan 50 MB, overweeg het met Git LFS op te slaan",
+		messageSummaryTitle:            "### `%s` gepubliceerd naar %s",
+		messageSummaryUpToDate:         "De branch was al bijgewerkt.",
+		messageSummaryCommit:           "Commit",
+		messageSummaryAdded:            "Toegevoegd",
+		messageSummaryModified:         "Gewijzigd",
+		messageSummaryDeleted:          "Verwijderd",
+		messageSummarySize:             "Totale grootte",
+		messagePushingTag:              "Tag '%s' pushen",
+		messageLoadStateFailed:         "Status laden mislukt: %v",
+		messageResolveRepositoryFailed: "Repository voor %s bepalen mislukt: %v",
+		messageSetOutputFailed:         "Output %s instellen mislukt: %v",
+		messageRefreshingToken:         "Inloggegevens geweigerd, token wordt vernieuwd",
+		messageURLManifest:             "URL-manifest met %d bestanden geschreven naar %s",
+		messageVerified:                "Branch '%s' op %s komt overeen met map '%s' (%d bestanden)",
+		messageVerifyMissing:           "ontbreekt op branch: %s",
+		messageVerifyDiffers:           "verschilt:           %s",
+		messageVerifyOnlyOnBranch:      "alleen op branch:    %s",
+	},
+}
+
+// locale is the active locale of the user-facing messages.
+var locale = defaultLocale
+
+// setLocale selects the locale of the user-facing messages from the input,
+// falling back to LANG, e.g. 'nl_NL.UTF-8', and then English.
+func setLocale(input string) {
+	for _, candidate := range []string{input, os.Getenv("LANG")} {
+		candidate = strings.ToLower(candidate)
+		candidate, _, _ = strings.Cut(candidate, ".")
+		candidate, _, _ = strings.Cut(candidate, "_")
+		candidate, _, _ = strings.Cut(candidate, "-")
+
+		if _, ok := catalog[candidate]; ok {
+			locale = candidate
+			return
+		}
+	}
+
+	locale = defaultLocale
+}
+
+// msg returns the message for key in the active locale, formatted with args.
+func msg(key string, args ...any) string {
+	format, ok := catalog[locale][key]
+	if !ok {
+		format = catalog[defaultLocale][key]
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
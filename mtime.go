@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// writeMtimeManifest records the modification times of the files copied from
+// the source into a JSON sidecar at name (relative to dir), since git does not
+// preserve mtimes. Kept and untouched files of the branch carry the time of
+// the clone, so they are left out to keep the manifest stable across runs.
+func writeMtimeManifest(cfg Config, dir, name string) error {
+	manifestPath := filepath.Join(dir, filepath.FromSlash(name))
+
+	files, err := copiedFiles(cfg)
+	if err != nil {
+		return err
+	}
+
+	mtimes := map[string]time.Time{}
+	for target, source := range files {
+		info, err := os.Stat(source)
+		if err != nil {
+			return err
+		}
+
+		mtimes[path.Join(contentPath(cfg), target)] = info.ModTime().UTC()
+	}
+
+	data, err := json.MarshalIndent(mtimes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(manifestPath, append(data, '\n'), 0o644)
+}
+
+// copiedFiles maps the slash separated paths, relative to the content
+// directory, of the files copied from the source to the files they were
+// copied from. Files matching the keep patterns are left out, as the branch
+// may hold its own version of them.
+func copiedFiles(cfg Config) (map[string]string, error) {
+	var kept pathFilter
+	if cfg.KeepFiles != "" {
+		kept = excludeFilter(splitList(cfg.KeepFiles))
+	}
+
+	files := map[string]string{}
+	if cfg.Manifest != "" {
+		folderConfig := cfg
+		folderConfig.Folder = sourceFolders(cfg)[0].Path
+
+		filters, err := sourceFilters(folderConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		entries, err := readManifest(cfg.Manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+
+		for _, entry := range entries {
+			if isFiltered(filters, splitPath(entry.Source), false) {
+				continue
+			}
+			files[filepath.ToSlash(entry.Target)] = filepath.Join(folderConfig.Folder, entry.Source)
+		}
+	} else {
+		sources, err := sourceFiles(cfg)
+		if err != nil {
+			return nil, err
+		}
+		files = sources
+	}
+
+	for name := range files {
+		if kept != nil && kept(strings.Split(name, "/"), false) {
+			delete(files, name)
+		}
+	}
+
+	return files, nil
+}
+
+// restoreMtimes applies the modification times recorded in an mtime manifest
+// to the files below root, for consumers checking out the published branch.
+func restoreMtimes(manifestPath, root string) error {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	mtimes := map[string]time.Time{}
+	if err := json.Unmarshal(data, &mtimes); err != nil {
+		return fmt.Errorf("failed to parse mtime manifest: %w", err)
+	}
+
+	for name, mtime := range mtimes {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+	}
+
+	fmt.Println(msg(messageRestoredMtimes, len(mtimes)))
+	return nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	publishDirectoryGroup   = "kontrolplane.io"
+	publishDirectoryVersion = "v1alpha1"
+	publishDirectoryPlural  = "publishdirectories"
+)
+
+// publishDirectoryResourceSpec is the desired state of a PublishDirectory
+// custom resource.
+type publishDirectoryResourceSpec struct {
+	Source struct {
+		ConfigMap string `json:"configMap,omitempty"`
+		Path      string `json:"path,omitempty"`
+		Git       *struct {
+			Repository string `json:"repository"`
+			Ref        string `json:"ref"`
+			Folder     string `json:"folder,omitempty"`
+		} `json:"git,omitempty"`
+	} `json:"source"`
+	Target struct {
+		Repository string `json:"repository"`
+		Branch     string `json:"branch"`
+	} `json:"target"`
+	Schedule      string `json:"schedule,omitempty"`
+	Policies      string `json:"policies,omitempty"`
+	CommitMessage string `json:"commitMessage,omitempty"`
+}
+
+// publishDirectoryResourceStatus is the observed state of a PublishDirectory.
+type publishDirectoryResourceStatus struct {
+	ObservedGeneration int64      `json:"observedGeneration,omitempty"`
+	Phase              string     `json:"phase,omitempty"`
+	Message            string     `json:"message,omitempty"`
+	Commit             string     `json:"commit,omitempty"`
+	LastSyncTime       *time.Time `json:"lastSyncTime,omitempty"`
+	// Failures counts the consecutive failed reconciles of FailedGeneration.
+	Failures         int   `json:"failures"`
+	FailedGeneration int64 `json:"failedGeneration"`
+}
+
+// publishDirectoryResource is a PublishDirectory custom resource.
+type publishDirectoryResource struct {
+	Metadata struct {
+		Name       string `json:"name"`
+		Namespace  string `json:"namespace"`
+		Generation int64  `json:"generation"`
+	} `json:"metadata"`
+	Spec   publishDirectoryResourceSpec   `json:"spec"`
+	Status publishDirectoryResourceStatus `json:"status"`
+}
+
+// phaseFailed is the phase of a resource whose last reconcile failed.
+const phaseFailed = "Failed"
+
+// maxFailureBackoff caps the time between retries of a failing resource.
+const maxFailureBackoff = time.Hour
+
+// failureBackoff returns how long to wait before retrying a resource that
+// failed the given number of times in a row.
+func failureBackoff(failures int) time.Duration {
+	return min(time.Minute<<min(max(failures-1, 0), 6), maxFailureBackoff)
+}
+
+// due reports whether the resource has to be reconciled: when its spec
+// changed since the last sync, when its schedule fired since then, or when
+// the backoff after a failed reconcile of its current spec passed.
+func (r publishDirectoryResource) due(now time.Time) bool {
+	if r.Status.Phase == phaseFailed && r.Status.FailedGeneration == r.Metadata.Generation && r.Status.LastSyncTime != nil {
+		return !now.Before(r.Status.LastSyncTime.Add(failureBackoff(r.Status.Failures)))
+	}
+
+	if r.Status.ObservedGeneration != r.Metadata.Generation || r.Status.LastSyncTime == nil {
+		return true
+	}
+
+	if r.Spec.Schedule == "" {
+		return false
+	}
+
+	schedule, err := parseCron(r.Spec.Schedule)
+	if err != nil {
+		return false
+	}
+
+	next, ok := schedule.next(*r.Status.LastSyncTime)
+	return ok && !next.After(now)
+}
+
+// runOperator reconciles the PublishDirectory resources of the cluster until
+// the process is stopped, checking them every reconcile interval.
+func runOperator(cfg Config) error {
+	client, err := newInClusterClient()
+	if err != nil {
+		return err
+	}
+
+	interval, err := time.ParseDuration(cfg.ReconcileInterval)
+	if err != nil {
+		return fmt.Errorf("invalid reconcile interval: %w", err)
+	}
+
+	path := fmt.Sprintf("/apis/%s/%s/%s", publishDirectoryGroup, publishDirectoryVersion, publishDirectoryPlural)
+	if cfg.WatchNamespace != "" {
+		path = fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s", publishDirectoryGroup, publishDirectoryVersion, cfg.WatchNamespace, publishDirectoryPlural)
+	}
+
+	fmt.Println(msg(messageReconcilingEvery, publishDirectoryPlural, interval))
+
+	for {
+		var list struct {
+			Items []publishDirectoryResource `json:"items"`
+		}
+
+		if err := client.request("GET", path, "", nil, &list); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageListResourcesFailed, publishDirectoryPlural, err))
+		}
+
+		for _, resource := range list.Items {
+			if resource.due(time.Now()) {
+				reconcileResource(cfg, client, resource)
+			}
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// reconcileResource publishes the source of the resource to its target and
+// records the outcome in its status.
+func reconcileResource(cfg Config, client *kubernetesClient, resource publishDirectoryResource) {
+	name := resource.Metadata.Namespace + "/" + resource.Metadata.Name
+	fmt.Println(msg(messageReconciling, name))
+
+	status := publishDirectoryResourceStatus{ObservedGeneration: resource.Metadata.Generation, Phase: "Published"}
+
+	commit, err := publishResource(cfg, client, resource)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, msg(messageReconcileFailed, name, err))
+
+		// The generation is only observed once it was published, failures
+		// are retried with a backoff.
+		status.ObservedGeneration = resource.Status.ObservedGeneration
+		status.Phase, status.Message = phaseFailed, err.Error()
+		status.FailedGeneration, status.Failures = resource.Metadata.Generation, 1
+		if resource.Status.Phase == phaseFailed && resource.Status.FailedGeneration == resource.Metadata.Generation {
+			status.Failures = resource.Status.Failures + 1
+		}
+	}
+
+	status.Commit = commit
+	if commit == "" {
+		status.Commit = resource.Status.Commit
+	}
+
+	now := time.Now().UTC()
+	status.LastSyncTime = &now
+
+	path := fmt.Sprintf("/apis/%s/%s/namespaces/%s/%s/%s/status", publishDirectoryGroup, publishDirectoryVersion,
+		resource.Metadata.Namespace, publishDirectoryPlural, resource.Metadata.Name)
+	patch := map[string]any{"status": status}
+	if err := client.request("PATCH", path, "application/merge-patch+json", patch, nil); err != nil {
+		fmt.Fprintln(os.Stderr, msg(messageStatusUpdateFailed, name, err))
+	}
+}
+
+// publishResource prepares the source of the resource and publishes it,
+// returning the published commit.
+func publishResource(cfg Config, client *kubernetesClient, resource publishDirectoryResource) (string, error) {
+	spec := resource.Spec
+
+	cfg.Repository = spec.Target.Repository
+	cfg.Branch = spec.Target.Branch
+	cfg.AssumeYes = true
+	if spec.CommitMessage != "" {
+		cfg.CommitMessage = spec.CommitMessage
+	}
+
+	if err := mintInstallationToken(&cfg, spec.Target.Repository); err != nil {
+		return "", err
+	}
+
+	workDirectory, err := os.MkdirTemp(temporaryRoot(), "kontrolplane-publish-directory-resource-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDirectory)
+
+	if spec.Policies != "" {
+		cfg.PolicyFile = filepath.Join(workDirectory, "policies")
+		if err := os.WriteFile(cfg.PolicyFile, []byte(spec.Policies), 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	switch {
+	case spec.Source.Git != nil:
+		if spec.Source.Git.Folder != "" && !filepath.IsLocal(filepath.FromSlash(spec.Source.Git.Folder)) {
+			return "", fmt.Errorf("source folder must be relative to the repository")
+		}
+
+		job := publishJob{Source: spec.Source.Git.Repository, Ref: spec.Source.Git.Ref}
+
+		dir, cleanup, err := cloneSource(cfg, job.Source, job.sourceReference())
+		if err != nil {
+			return "", err
+		}
+		defer cleanup()
+
+		cfg.Folder = filepath.Join(dir, filepath.FromSlash(spec.Source.Git.Folder))
+		if _, err := os.Stat(cfg.Folder); err != nil {
+			return "", fmt.Errorf("source folder '%s' does not exist", spec.Source.Git.Folder)
+		}
+		if err := checkWorkspaceContainment(cfg.Folder, dir); err != nil {
+			return "", err
+		}
+
+	case spec.Source.ConfigMap != "":
+		cfg.Folder = filepath.Join(workDirectory, "source")
+		if err := writeConfigMap(client, resource.Metadata.Namespace, spec.Source.ConfigMap, cfg.Folder); err != nil {
+			return "", err
+		}
+
+	case spec.Source.Path != "":
+		// Paths give access to the file system of the operator, so they are
+		// confined to the directory in which sources are mounted.
+		if cfg.SourcePathRoot == "" {
+			return "", fmt.Errorf("path sources are disabled, set source_path_root to allow them")
+		}
+
+		if !filepath.IsLocal(filepath.FromSlash(spec.Source.Path)) {
+			return "", fmt.Errorf("source path '%s' must be relative to the source path root", spec.Source.Path)
+		}
+
+		cfg.Folder = filepath.Join(cfg.SourcePathRoot, filepath.FromSlash(spec.Source.Path))
+		if err := checkWorkspaceContainment(cfg.Folder, cfg.SourcePathRoot); err != nil {
+			return "", err
+		}
+
+	default:
+		return "", fmt.Errorf("source needs a configMap, path or git repository")
+	}
+
+	if _, err := os.Stat(cfg.Folder); err != nil {
+		return "", fmt.Errorf("source folder '%s' does not exist", cfg.Folder)
+	}
+
+	return publishCommit(cfg)
+}
+
+// writeConfigMap writes every key of the config map as a file into dir.
+func writeConfigMap(client *kubernetesClient, namespace, name, dir string) error {
+	var configMap struct {
+		Data       map[string]string `json:"data"`
+		BinaryData map[string]string `json:"binaryData"`
+	}
+
+	if err := client.request("GET", fmt.Sprintf("/api/v1/namespaces/%s/configmaps/%s", namespace, name), "", nil, &configMap); err != nil {
+		return fmt.Errorf("failed to read config map '%s': %w", name, err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for key, value := range configMap.Data {
+		if err := os.WriteFile(filepath.Join(dir, key), []byte(value), 0o644); err != nil {
+			return err
+		}
+	}
+
+	for key, value := range configMap.BinaryData {
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("invalid binary data '%s' in config map '%s': %w", key, name, err)
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, key), data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// setOutput writes a step output to the file referenced by GITHUB_OUTPUT, and
+// to stdout with JSON output enabled. The file is not written when running
+// outside of GitHub Actions.
+func setOutput(name, value string) error {
+	if err := writeJSONOutput(name, value); err != nil {
+		return err
+	}
+	return writeCommandFile("GITHUB_OUTPUT", name, value)
+}
+
+// setPublishOutputs writes the outputs describing the result of a publish, so
+// later steps can react to it without parsing the log. When the branch was
+// already up to date commit_sha is the commit the branch points at.
+func setPublishOutputs(repo *git.Repository, branch string, pushed bool, changes changeStats) error {
+	commit := ""
+	if head, err := repo.Head(); err == nil {
+		commit = head.Hash().String()
+	}
+
+	outputs := map[string]string{
+		"commit_sha":    commit,
+		"branch":        branch,
+		"pushed":        strconv.FormatBool(pushed),
+		"changed_files": strconv.Itoa(changes.files()),
+	}
+	for name, value := range outputs {
+		if err := setOutput(name, value); err != nil {
+			return fmt.Errorf("failed to set output %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// saveActionState writes a value to the file referenced by GITHUB_STATE, which
+// is passed to the post step as the STATE_ prefixed environment variable.
+func saveActionState(name, value string) error {
+	return writeCommandFile("GITHUB_STATE", name, value)
+}
+
+// writeCommandFile appends a name and value to the workflow command file
+// referenced by the variable. It is a no-op when the variable is not set.
+func writeCommandFile(variable, name, value string) error {
+	path := os.Getenv(variable)
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if strings.Contains(value, "\n") {
+		delimiter := "EOF_" + randomUUID()
+		_, err = fmt.Fprintf(file, "%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter)
+		return err
+	}
+
+	_, err = fmt.Fprintf(file, "%s=%s\n", name, value)
+	return err
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// noJekyllFile disables the Jekyll build of GitHub Pages, which drops files
+// and directories starting with an underscore.
+const noJekyllFile = ".nojekyll"
+
+// cnameFile holds the custom domain of a GitHub Pages site.
+const cnameFile = "CNAME"
+
+// writeNoJekyll creates an empty .nojekyll file in dir unless it exists.
+func writeNoJekyll(dir string) error {
+	path := filepath.Join(dir, noJekyllFile)
+	if _, err := os.Lstat(path); err == nil {
+		return nil
+	}
+
+	return os.WriteFile(path, nil, 0o644)
+}
+
+// writeCNAME writes the custom domain to the CNAME file in dir, replacing any
+// CNAME file copied from the folder.
+func writeCNAME(dir, domain string) error {
+	return os.WriteFile(filepath.Join(dir, cnameFile), []byte(domain+"\n"), 0o644)
+}
+
+// pagesBuildPollInterval is how often the status of a Pages build is read.
+const pagesBuildPollInterval = 10 * time.Second
+
+// pagesSource is the branch and path GitHub Pages publishes from.
+type pagesSource struct {
+	Branch string `json:"branch"`
+	Path   string `json:"path"`
+}
+
+// configurePages enables GitHub Pages for repository, publishing from the
+// branch, when Pages is not configured yet. An existing configuration is left
+// untouched.
+func configurePages(cfg Config, repository string) error {
+	source := pagesSource{Branch: cfg.Branch, Path: "/"}
+	if cfg.TargetDir != "" {
+		source.Path = path.Clean("/" + filepath.ToSlash(cfg.TargetDir))
+	}
+
+	if source.Path != "/" && source.Path != "/docs" {
+		return fmt.Errorf("GitHub Pages can only publish from the root or the docs directory of a branch, not '%s'", source.Path)
+	}
+
+	var site struct {
+		Source pagesSource `json:"source"`
+	}
+	err := githubRequest(cfg.GithubToken, "GET", fmt.Sprintf("/repos/%s/pages", repository), nil, &site)
+
+	var apiErr *githubAPIError
+	switch {
+	case err == nil:
+		if site.Source != source {
+			fmt.Println(msg(messagePagesUnchanged, site.Source.Branch, site.Source.Path))
+		}
+		return nil
+	case !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound:
+		return fmt.Errorf("failed to read GitHub Pages configuration: %w", err)
+	}
+
+	if err := githubRequest(cfg.GithubToken, "POST", fmt.Sprintf("/repos/%s/pages", repository), map[string]any{"source": source}, nil); err != nil {
+		return fmt.Errorf("failed to enable GitHub Pages: %w", err)
+	}
+
+	fmt.Println(msg(messagePagesEnabled, source.Branch, source.Path))
+	return nil
+}
+
+// pagesBuild is a GitHub Pages build, as returned by the Pages builds API.
+type pagesBuild struct {
+	Status string `json:"status"`
+	Commit string `json:"commit"`
+	Error  struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// triggerPagesBuild requests a GitHub Pages build of repository and, with a
+// timeout, waits until the build of commit succeeds or fails. The URL of the
+// site is set as the pages_url output.
+func triggerPagesBuild(cfg Config, repository, commit string) error {
+	if err := githubRequest(cfg.GithubToken, "POST", fmt.Sprintf("/repos/%s/pages/builds", repository), nil, nil); err != nil {
+		return fmt.Errorf("failed to request GitHub Pages build: %w", err)
+	}
+
+	var site struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := githubRequest(cfg.GithubToken, "GET", fmt.Sprintf("/repos/%s/pages", repository), nil, &site); err != nil {
+		return fmt.Errorf("failed to read GitHub Pages configuration: %w", err)
+	}
+
+	if err := setOutput("pages_url", site.HTMLURL); err != nil {
+		return err
+	}
+
+	if cfg.PagesBuildTimeout == "" {
+		fmt.Println(msg(messagePagesBuildRequested))
+		return nil
+	}
+
+	timeout, err := time.ParseDuration(cfg.PagesBuildTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid pages build timeout: %w", err)
+	}
+
+	fmt.Println(msg(messagePagesBuildWaiting, timeout))
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		var build pagesBuild
+		if err := githubRequest(cfg.GithubToken, "GET", fmt.Sprintf("/repos/%s/pages/builds/latest", repository), nil, &build); err != nil {
+			return fmt.Errorf("failed to read GitHub Pages build: %w", err)
+		}
+
+		if build.Commit == commit {
+			switch build.Status {
+			case "built":
+				fmt.Println(msg(messagePagesDeployed, site.HTMLURL))
+				return nil
+			case "errored":
+				return fmt.Errorf("GitHub Pages build of %s failed: %s", commit, build.Error.Message)
+			}
+		}
+
+		time.Sleep(pagesBuildPollInterval)
+	}
+
+	return fmt.Errorf("GitHub Pages build did not finish within %s", timeout)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+// shellCommand returns a command running a user supplied shell command. On
+// Windows, where sh is usually missing, the bash shipped with Git for Windows
+// is used when available and cmd otherwise.
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS != "windows" {
+		return exec.Command("sh", "-c", command)
+	}
+
+	if bash, err := exec.LookPath("bash"); err == nil {
+		return exec.Command(bash, "-c", command)
+	}
+	return exec.Command("cmd", "/C", command)
+}
+
+// temporaryRoot returns the directory temporary files are created in,
+// preferring the runner's temporary directory, which is cleaned up between
+// jobs and, on Windows, is not subject to 8.3 short names.
+func temporaryRoot() string {
+	if dir := os.Getenv("RUNNER_TEMP"); dir != "" {
+		return dir
+	}
+	return os.TempDir()
+}
+
+// preserveExecutableModes restores the executable mode of staged files that
+// are executable on the branch. Windows file systems have no executable bit,
+// so without this every publish from Windows would mark them as regular files.
+func preserveExecutableModes(repo *git.Repository) error {
+	if runtime.GOOS != "windows" {
+		return nil
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	branchMode := func(name string) filemode.FileMode {
+		file, err := tree.File(name)
+		if err != nil {
+			return filemode.Empty
+		}
+		return file.Mode
+	}
+
+	if !restoreExecutableModes(idx, branchMode) {
+		return nil
+	}
+	return repo.Storer.SetIndex(idx)
+}
+
+// restoreExecutableModes marks the regular files of the index that are
+// executable on the branch as executable, and reports whether it changed any.
+func restoreExecutableModes(idx *index.Index, branchMode func(name string) filemode.FileMode) bool {
+	changed := false
+	for _, entry := range idx.Entries {
+		if entry.Mode != filemode.Regular || branchMode(entry.Name) != filemode.Executable {
+			continue
+		}
+
+		entry.Mode = filemode.Executable
+		changed = true
+	}
+
+	return changed
+}
+
+// ignoreModeChanges drops the worktree changes of files whose content matches
+// the index. On Windows the worktree has no executable bit, so every file
+// preserveExecutableModes restored would otherwise count as modified and the
+// branch would never be considered unchanged.
+func ignoreModeChanges(repo *git.Repository, status git.Status) error {
+	if runtime.GOOS != "windows" {
+		return nil
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return err
+	}
+
+	worktreeHash := func(name string) (plumbing.Hash, error) {
+		content, err := util.ReadFile(worktree.Filesystem, name)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return plumbing.ComputeHash(plumbing.BlobObject, content), nil
+	}
+
+	return dropModeChanges(status, idx, worktreeHash)
+}
+
+// dropModeChanges marks the executable files of the index that are modified
+// in the worktree as unmodified when their worktree content hashes the same.
+func dropModeChanges(status git.Status, idx *index.Index, worktreeHash func(name string) (plumbing.Hash, error)) error {
+	for _, entry := range idx.Entries {
+		fileStatus, ok := status[entry.Name]
+		if !ok || fileStatus.Worktree != git.Modified || entry.Mode != filemode.Executable {
+			continue
+		}
+
+		hash, err := worktreeHash(entry.Name)
+		if err != nil {
+			return err
+		}
+
+		if hash == entry.Hash {
+			fileStatus.Worktree = git.Unmodified
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+func TestRestoreExecutableModes(t *testing.T) {
+	branch := map[string]filemode.FileMode{
+		"run.sh":         filemode.Executable,
+		"scripts/run.sh": filemode.Executable,
+		"README.md":      filemode.Regular,
+	}
+
+	tests := []struct {
+		name    string
+		mode    filemode.FileMode
+		want    filemode.FileMode
+		changed bool
+	}{
+		{name: "run.sh", mode: filemode.Regular, want: filemode.Executable, changed: true},
+		{name: "scripts/run.sh", mode: filemode.Regular, want: filemode.Executable, changed: true},
+		{name: "scripts\\run.sh", mode: filemode.Regular, want: filemode.Regular},
+		{name: "README.md", mode: filemode.Regular, want: filemode.Regular},
+		{name: "new.sh", mode: filemode.Regular, want: filemode.Regular},
+		{name: "run.sh", mode: filemode.Symlink, want: filemode.Symlink},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			idx := &index.Index{Entries: []*index.Entry{{Name: test.name, Mode: test.mode}}}
+
+			changed := restoreExecutableModes(idx, func(name string) filemode.FileMode {
+				if mode, ok := branch[name]; ok {
+					return mode
+				}
+				return filemode.Empty
+			})
+
+			if changed != test.changed {
+				t.Errorf("changed = %v, want %v", changed, test.changed)
+			}
+			if mode := idx.Entries[0].Mode; mode != test.want {
+				t.Errorf("mode = %v, want %v", mode, test.want)
+			}
+		})
+	}
+}
+
+func TestDropModeChanges(t *testing.T) {
+	content := plumbing.ComputeHash(plumbing.BlobObject, []byte("#!/bin/sh\n"))
+	edited := plumbing.ComputeHash(plumbing.BlobObject, []byte("#!/bin/bash\n"))
+
+	tests := []struct {
+		name     string
+		mode     filemode.FileMode
+		worktree git.StatusCode
+		hash     plumbing.Hash
+		want     git.StatusCode
+	}{
+		{name: "mode only", mode: filemode.Executable, worktree: git.Modified, hash: content, want: git.Unmodified},
+		{name: "nested/mode only", mode: filemode.Executable, worktree: git.Modified, hash: content, want: git.Unmodified},
+		{name: "content changed", mode: filemode.Executable, worktree: git.Modified, hash: edited, want: git.Modified},
+		{name: "regular file", mode: filemode.Regular, worktree: git.Modified, hash: content, want: git.Modified},
+		{name: "deleted", mode: filemode.Executable, worktree: git.Deleted, hash: content, want: git.Deleted},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			idx := &index.Index{Entries: []*index.Entry{{Name: test.name, Mode: test.mode, Hash: content}}}
+			status := git.Status{test.name: {Staging: git.Unmodified, Worktree: test.worktree}}
+
+			err := dropModeChanges(status, idx, func(name string) (plumbing.Hash, error) {
+				if name != test.name {
+					t.Errorf("hashed '%s', want '%s'", name, test.name)
+				}
+				return test.hash, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if code := status[test.name].Worktree; code != test.want {
+				t.Errorf("worktree status = %q, want %q", code, test.want)
+			}
+			if want := test.want != git.Unmodified; hasChanges(status, nil) != want {
+				t.Errorf("hasChanges = %v, want %v", !want, want)
+			}
+		})
+	}
+}
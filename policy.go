@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// policyRule is a single CEL expression evaluated against the planned publish.
+// When the expression evaluates to true the rule's effect applies.
+type policyRule struct {
+	Effect     string
+	Expression string
+	Message    string
+	Line       int
+}
+
+// readPolicies parses a policy file. Every non-empty line that does not start
+// with '#' has the form '<deny|warn> <CEL expression> : <message>'.
+func readPolicies(path string) ([]policyRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []policyRule
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		effect, rest, _ := strings.Cut(line, " ")
+		if effect != "deny" && effect != "warn" {
+			return nil, fmt.Errorf("line %d: rule must start with 'deny' or 'warn'", lineNumber)
+		}
+
+		separator := strings.LastIndex(rest, " : ")
+		if separator < 0 {
+			return nil, fmt.Errorf("line %d: rule is missing ' : <message>'", lineNumber)
+		}
+
+		rules = append(rules, policyRule{
+			Effect:     effect,
+			Expression: strings.TrimSpace(rest[:separator]),
+			Message:    strings.TrimSpace(rest[separator+3:]),
+			Line:       lineNumber,
+		})
+	}
+
+	return rules, scanner.Err()
+}
+
+// policyInput builds the variables policies are evaluated against from the
+// prepared worktree.
+func policyInput(cfg Config, dir, repository string) (map[string]any, error) {
+	var files []map[string]any
+	var totalSize int64
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, map[string]any{
+			"path": filepath.ToSlash(relativePath),
+			"size": info.Size(),
+		})
+		totalSize += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"files":      files,
+		"file_count": int64(len(files)),
+		"total_size": totalSize,
+		"branch":     cfg.Branch,
+		"repository": repository,
+		"actor":      os.Getenv("GITHUB_ACTOR"),
+		"event":      os.Getenv("GITHUB_EVENT_NAME"),
+	}, nil
+}
+
+// evaluatePolicies evaluates the rules in the policy file against the planned
+// publish, printing warnings and failing when any deny rule matches.
+func evaluatePolicies(cfg Config, dir, repository string) error {
+	rules, err := readPolicies(cfg.PolicyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read policies: %w", err)
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("files", cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+		cel.Variable("file_count", cel.IntType),
+		cel.Variable("total_size", cel.IntType),
+		cel.Variable("branch", cel.StringType),
+		cel.Variable("repository", cel.StringType),
+		cel.Variable("actor", cel.StringType),
+		cel.Variable("event", cel.StringType),
+	)
+	if err != nil {
+		return err
+	}
+
+	input, err := policyInput(cfg, dir, repository)
+	if err != nil {
+		return err
+	}
+
+	var denials []string
+	for _, rule := range rules {
+		ast, issues := env.Compile(rule.Expression)
+		if issues != nil && issues.Err() != nil {
+			return fmt.Errorf("policy on line %d: %w", rule.Line, issues.Err())
+		}
+
+		if ast.OutputType() != cel.BoolType {
+			return fmt.Errorf("policy on line %d must evaluate to a bool", rule.Line)
+		}
+
+		program, err := env.Program(ast)
+		if err != nil {
+			return fmt.Errorf("policy on line %d: %w", rule.Line, err)
+		}
+
+		result, _, err := program.Eval(input)
+		if err != nil {
+			return fmt.Errorf("policy on line %d: %w", rule.Line, err)
+		}
+
+		if matched, _ := result.Value().(bool); !matched {
+			continue
+		}
+
+		if rule.Effect == "warn" {
+			fmt.Printf("::warning title=Policy::%s\n", rule.Message)
+			continue
+		}
+
+		fmt.Printf("::error title=Policy::%s\n", rule.Message)
+		denials = append(denials, rule.Message)
+	}
+
+	if len(denials) > 0 {
+		return fmt.Errorf("publish denied by policy: %s", strings.Join(denials, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// postStateVariable is set in the environment of the post step through the
+// state saved by the main step.
+const postStateVariable = "STATE_post"
+
+// publishRecord describes a branch update made by the main step, so that the
+// post step can revert it.
+type publishRecord struct {
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"`
+	Previous   string `json:"previous"`
+	Published  string `json:"published"`
+}
+
+// publishRecords holds the branch updates made by this run, or by the current
+// job in the server and operator modes.
+var publishRecords []publishRecord
+
+// recordPublish remembers a pushed branch update for the post step.
+func recordPublish(record publishRecord) error {
+	publishRecords = append(publishRecords, record)
+
+	data, err := json.Marshal(publishRecords)
+	if err != nil {
+		return err
+	}
+
+	return saveActionState("published", string(data))
+}
+
+// runPostStep removes the temporary data left behind by the main step and,
+// when enabled and the job failed, reverts the branch updates it made. The
+// work directory of the state file is kept, so that a failed publish can be
+// resumed by a later run.
+func runPostStep(cfg Config) error {
+	matches, err := filepath.Glob(filepath.Join(temporaryRoot(), "kontrolplane-publish-directory-*"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range matches {
+		if err := os.RemoveAll(path); err != nil {
+			fmt.Printf("::warning title=Cleanup::%s\n", msg(messageRemoveFailed, path, err))
+		}
+	}
+
+	if !cfg.RevertOnFailure || os.Getenv("STATE_published") == "" {
+		return nil
+	}
+
+	failed, err := jobFailed(cfg.GithubToken)
+	if err != nil {
+		return fmt.Errorf("failed to determine the job status: %w", err)
+	}
+
+	if !failed {
+		return nil
+	}
+
+	var records []publishRecord
+	if err := json.Unmarshal([]byte(os.Getenv("STATE_published")), &records); err != nil {
+		return fmt.Errorf("failed to read published branches: %w", err)
+	}
+
+	var failures int
+	for _, record := range records {
+		if err := revertPublish(cfg, record); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageRevertFailed, target{Repository: record.Repository, Branch: record.Branch}, err))
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d publishes could not be reverted", failures, len(records))
+	}
+	return nil
+}
+
+// revertPublish resets the branch to its state before the publish, deleting it
+// when it was created by the publish. The branch is left alone when it moved
+// since the publish.
+func revertPublish(cfg Config, record publishRecord) error {
+	cfg.Repository, cfg.Branch = record.Repository, record.Branch
+
+	_, url, auth, err := resolveRemote(cfg)
+	if err != nil {
+		return err
+	}
+
+	branchReference := plumbing.NewBranchReferenceName(record.Branch)
+
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return err
+	}
+
+	remote, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{url}})
+	if err != nil {
+		return err
+	}
+
+	refSpec := config.RefSpec(":" + branchReference.String())
+	if record.Previous != plumbing.ZeroHash.String() {
+		err = remote.Fetch(&git.FetchOptions{
+			Auth:     auth,
+			RefSpecs: []config.RefSpec{config.RefSpec(record.Previous + ":refs/revert/previous")},
+			Depth:    1,
+		})
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			// Servers that do not allow fetching commits by hash still serve
+			// the previous commit as an ancestor of the published one.
+			err = remote.Fetch(&git.FetchOptions{
+				Auth:     auth,
+				RefSpecs: []config.RefSpec{config.RefSpec(branchReference.String() + ":refs/revert/published")},
+			})
+		}
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			return fmt.Errorf("failed to fetch %s: %w", record.Previous, err)
+		}
+		refSpec = config.RefSpec("+" + record.Previous + ":" + branchReference.String())
+	}
+
+	fmt.Println(msg(messageReverting, target{Repository: record.Repository, Branch: record.Branch}, record.Previous))
+
+	return remote.Push(&git.PushOptions{
+		Auth:              auth,
+		RefSpecs:          []config.RefSpec{refSpec},
+		RequireRemoteRefs: []config.RefSpec{config.RefSpec(record.Published + ":" + branchReference.String())},
+	})
+}
+
+// jobFailed reports whether a step of the current job failed, using the
+// workflow run API.
+func jobFailed(token string) (bool, error) {
+	var response struct {
+		Jobs []struct {
+			Status     string `json:"status"`
+			RunnerName string `json:"runner_name"`
+			Steps      []struct {
+				Conclusion string `json:"conclusion"`
+			} `json:"steps"`
+		} `json:"jobs"`
+	}
+
+	path := fmt.Sprintf("/repos/%s/actions/runs/%s/attempts/%s/jobs?per_page=100",
+		os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"), os.Getenv("GITHUB_RUN_ATTEMPT"))
+	if err := githubRequest(token, "GET", path, nil, &response); err != nil {
+		return false, err
+	}
+
+	for _, job := range response.Jobs {
+		if job.Status != "in_progress" || job.RunnerName != os.Getenv("RUNNER_NAME") {
+			continue
+		}
+
+		for _, step := range job.Steps {
+			if step.Conclusion == "failure" {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Providers hosting the repository published to.
+const (
+	providerGithub    = "github"
+	providerGitlab    = "gitlab"
+	providerBitbucket = "bitbucket"
+	providerGitea     = "gitea"
+	providerForgejo   = "forgejo"
+	providerAzure     = "azure-devops"
+)
+
+// gitProvider is a hosting provider of the repositories published to, which
+// knows how to address them and how to authenticate to them.
+type gitProvider interface {
+	// remote returns the clone URL of repository and the credentials to
+	// access it.
+	remote(cfg Config, repository string) (string, transport.AuthMethod, error)
+}
+
+var providers = map[string]gitProvider{
+	providerGithub:    githubProvider{},
+	providerGitlab:    gitlabProvider{},
+	providerBitbucket: bitbucketProvider{},
+	providerGitea:     giteaProvider{},
+	providerForgejo:   giteaProvider{},
+	providerAzure:     azureProvider{},
+}
+
+// remoteProvider returns the name of the provider hosting the repository
+// published to. Unless configured it is derived from the token given.
+func remoteProvider(cfg Config) string {
+	switch {
+	case cfg.Provider != "":
+		return cfg.Provider
+	case cfg.GitlabToken != "":
+		return providerGitlab
+	case cfg.BitbucketToken != "":
+		return providerBitbucket
+	case cfg.GiteaToken != "":
+		return providerGitea
+	case cfg.AzureDevOpsToken != "":
+		return providerAzure
+	default:
+		return providerGithub
+	}
+}
+
+// providerURL returns the clone URL of repository on the instance at base.
+// A repository given as a full clone URL is returned as is.
+func providerURL(base, repository string) string {
+	if strings.Contains(repository, "://") {
+		return repository
+	}
+	return fmt.Sprintf("%s/%s.git", base, repository)
+}
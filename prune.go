@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// parseAge parses a duration such as 72h, additionally accepting a number of
+// days such as 30d.
+func parseAge(input string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(input, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil || count < 0 {
+			return 0, fmt.Errorf("invalid max age '%s'", input)
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+
+	age, err := time.ParseDuration(input)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid max age '%s', expected e.g. 30d or 72h", input)
+	}
+	return age, nil
+}
+
+// pruneBranches deletes the remote branches matching the prune patterns whose
+// tip was committed longer than the max age ago.
+func pruneBranches(cfg Config) error {
+	repository, url, auth, err := resolveRemote(cfg)
+	if err != nil {
+		return err
+	}
+
+	maxAge, err := parseAge(cfg.MaxAge)
+	if err != nil {
+		return err
+	}
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{Name: "origin", URLs: []string{url}})
+	references, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		return fmt.Errorf("failed to list remote branches: %w", err)
+	}
+
+	patterns := splitList(cfg.PruneBranches)
+
+	var candidates []string
+	var refSpecs []config.RefSpec
+	for _, reference := range references {
+		if !reference.Name().IsBranch() {
+			continue
+		}
+
+		branch := reference.Name().Short()
+		if isWorkflowBranch(repository, branch) || !matchesAny(patterns, branch) {
+			continue
+		}
+
+		candidates = append(candidates, branch)
+		refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("+%s:%s", reference.Name(), plumbing.NewRemoteReferenceName("origin", branch))))
+	}
+
+	if len(candidates) == 0 {
+		fmt.Println(msg(messageNoPruneMatches))
+		return setOutput("pruned_branches", "")
+	}
+
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{url}}); err != nil {
+		return err
+	}
+
+	if err := repo.Fetch(&git.FetchOptions{RemoteName: "origin", Auth: auth, RefSpecs: refSpecs, Depth: 1}); err != nil {
+		return fmt.Errorf("failed to fetch branches: %w", err)
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+
+	var stale []string
+	for _, branch := range candidates {
+		reference, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", branch), true)
+		if err != nil {
+			return fmt.Errorf("failed to resolve branch '%s': %w", branch, err)
+		}
+
+		commit, err := repo.CommitObject(reference.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to read tip of branch '%s': %w", branch, err)
+		}
+
+		if commit.Committer.When.Before(cutoff) {
+			fmt.Println(msg(messageLastPublished, branch, commit.Committer.When.UTC().Format(time.RFC3339)))
+			stale = append(stale, branch)
+		}
+	}
+
+	if len(stale) > 0 && !cfg.DryRun {
+		if err := deleteRemoteBranches(cfg, remote, auth, stale); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println(msg(messagePruned, len(stale), len(candidates), cfg.MaxAge))
+	return setOutput("pruned_branches", strings.Join(stale, "\n"))
+}
+
+// matchesAny reports whether name matches one of the glob patterns, in which
+// '*' does not match a slash.
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// githubRelease is the subset of a GitHub release needed to attach assets.
+type githubRelease struct {
+	ID        int64          `json:"id"`
+	UploadURL string         `json:"upload_url"`
+	HTMLURL   string         `json:"html_url"`
+	Assets    []releaseAsset `json:"assets"`
+}
+
+// releaseAsset is an asset attached to a GitHub release.
+type releaseAsset struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// archiveFormats are the release archive formats writeArchive can create.
+var archiveFormats = []string{"tar.gz", "tgz", "zip"}
+
+// releaseTag returns the tag of the release to create, with {branch} and the
+// run context placeholders expanded.
+func releaseTag(cfg Config) string {
+	return expandTemplate(strings.ReplaceAll(cfg.ReleaseTag, "{branch}", cfg.Branch), time.Now())
+}
+
+// publishRelease archives the folder in the configured formats and attaches
+// the archives to a GitHub release of repository, tagging target when the tag
+// does not exist yet. An existing release for the tag is reused, with assets of
+// the same name replaced.
+func publishRelease(cfg Config, repository, target string) error {
+	tag := releaseTag(cfg)
+
+	dir, err := os.MkdirTemp(temporaryRoot(), "kontrolplane-publish-directory-release-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := sourceFiles(cfg)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Base(filepath.Clean(sourceFolders(cfg)[0].Path)) + "-" + strings.ReplaceAll(tag, "/", "-")
+
+	var assets []string
+	for _, format := range splitList(cfg.ReleaseFormats) {
+		asset := filepath.Join(dir, name+"."+format)
+		if err := writeArchive(files, asset, format); err != nil {
+			return fmt.Errorf("failed to create %s archive: %w", format, err)
+		}
+		assets = append(assets, asset)
+	}
+
+	body := map[string]any{"tag_name": tag, "name": tag}
+	if target != "" {
+		body["target_commitish"] = target
+	}
+
+	var release githubRelease
+	err = githubRequest(cfg.GithubToken, "GET", fmt.Sprintf("/repos/%s/releases/tags/%s", repository, url.PathEscape(tag)), nil, &release)
+
+	var apiErr *githubAPIError
+	existing := err == nil
+	switch {
+	case existing:
+	case !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound:
+		return fmt.Errorf("failed to read release '%s': %w", tag, err)
+	default:
+		if err := githubRequest(cfg.GithubToken, "POST", fmt.Sprintf("/repos/%s/releases", repository), body, &release); err != nil {
+			return fmt.Errorf("failed to create release '%s': %w", tag, err)
+		}
+	}
+
+	for _, asset := range assets {
+		// Asset names are unique within a release, so an asset uploaded by an
+		// earlier run is deleted before its replacement is uploaded.
+		index := slices.IndexFunc(release.Assets, func(a releaseAsset) bool { return a.Name == filepath.Base(asset) })
+		if index >= 0 {
+			fmt.Println(msg(messageReplacingAsset, filepath.Base(asset)))
+			if err := githubRequest(cfg.GithubToken, "DELETE", fmt.Sprintf("/repos/%s/releases/assets/%d", repository, release.Assets[index].ID), nil, nil); err != nil {
+				return fmt.Errorf("failed to delete release asset '%s': %w", filepath.Base(asset), err)
+			}
+		} else {
+			fmt.Println(msg(messageUploadingAsset, filepath.Base(asset)))
+		}
+		if err := uploadReleaseAsset(cfg.GithubToken, release.UploadURL, asset); err != nil {
+			return fmt.Errorf("failed to upload release asset '%s': %w", filepath.Base(asset), err)
+		}
+	}
+
+	if existing {
+		fmt.Println(msg(messageReleaseUpdated, release.HTMLURL))
+	} else {
+		fmt.Println(msg(messageReleasePublished, release.HTMLURL))
+	}
+	return setOutput("release_url", release.HTMLURL)
+}
+
+// uploadReleaseAsset uploads the file at path to the upload URL of a release,
+// which is a URI template ending in {?name,label}.
+func uploadReleaseAsset(token, uploadURL, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	endpoint, _, _ := strings.Cut(uploadURL, "{")
+	request, err := http.NewRequest(http.MethodPost, endpoint+"?name="+url.QueryEscape(filepath.Base(path)), file)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = info.Size()
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/octet-stream")
+
+	client := &http.Client{Timeout: 10 * time.Minute}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("upload returned %s", response.Status)
+	}
+
+	return nil
+}
+
+// writeArchive writes the files, keyed by their name in the archive, to a
+// tar.gz or zip archive at path.
+func writeArchive(files map[string]string, path, format string) error {
+	output, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	var add archiveAdder
+	var closers []io.Closer
+
+	switch format {
+	case "tar.gz", "tgz":
+		compressed := gzip.NewWriter(output)
+		archive := tar.NewWriter(compressed)
+		closers = append(closers, archive, compressed)
+
+		add = func(name string, info os.FileInfo, file io.Reader) error {
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			if err := archive.WriteHeader(header); err != nil {
+				return err
+			}
+			_, err = io.Copy(archive, file)
+			return err
+		}
+	case "zip":
+		archive := zip.NewWriter(output)
+		closers = append(closers, archive)
+
+		add = func(name string, info os.FileInfo, file io.Reader) error {
+			header, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			header.Method = zip.Deflate
+			writer, err := archive.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(writer, file)
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown archive format '%s', expected tar.gz or zip", format)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := addArchiveFile(add, name, files[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+
+	return output.Close()
+}
+
+// archiveAdder adds a file with the given name and content to an archive.
+type archiveAdder func(name string, info os.FileInfo, file io.Reader) error
+
+// addArchiveFile adds the file at path to an archive under name.
+func addArchiveFile(add archiveAdder, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return add(name, info, file)
+}
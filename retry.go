@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// isTransientPushError reports whether err is a network failure or server
+// error that is likely to succeed when the push is retried.
+func isTransientPushError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var unexpected *plumbing.UnexpectedError
+	if errors.As(err, &unexpected) {
+		var httpErr *http.Err
+		if errors.As(unexpected.Err, &httpErr) {
+			return httpErr.StatusCode() >= 500
+		}
+	}
+
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "connection reset") || strings.Contains(message, "unexpected eof")
+}
+
+// isNonFastForward reports whether err is the push being rejected because the
+// branch moved since it was cloned.
+func isNonFastForward(err error) bool {
+	return errors.Is(err, git.ErrNonFastForwardUpdate) || strings.Contains(strings.ToLower(err.Error()), "non-fast-forward")
+}
+
+// retryPush runs push, retrying transient failures up to the configured
+// number of times with a jittered, exponentially growing delay. With the
+// status check enabled it fails fast during a GitHub incident instead.
+func retryPush(cfg Config, push func() error) error {
+	delay, err := time.ParseDuration(cfg.PushRetryDelay)
+	if err != nil {
+		return fmt.Errorf("invalid push retry delay: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := push()
+		if err == nil || attempt >= cfg.PushRetries || !isTransientPushError(err) {
+			return err
+		}
+
+		// Retrying during an incident only delays the failure.
+		if checksGithubStatus(cfg) {
+			var incidentErr *githubIncidentError
+			if err := incidentError(err); errors.As(err, &incidentErr) {
+				return err
+			}
+		}
+
+		// A jitter of up to half the delay either way keeps concurrent
+		// publishes from retrying in lockstep.
+		wait := delay<<attempt/2 + time.Duration(rand.Int64N(int64(delay<<attempt)+1))
+		fmt.Println(msg(messagePushRetry, err, wait.Round(time.Millisecond), attempt+1, cfg.PushRetries))
+		time.Sleep(wait)
+	}
+}
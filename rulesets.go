@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// blockingRules maps the types of repository rules that reject a direct push
+// by the action to a description of what they require.
+var blockingRules = map[string]string{
+	"pull_request":           "changes must be made through a pull request",
+	"required_signatures":    "commits must be signed",
+	"required_status_checks": "status checks must pass before changes land",
+	"update":                 "only bypass actors may update the branch",
+	"required_deployments":   "deployments must succeed before changes land",
+	"merge_queue":            "changes must go through the merge queue",
+	"required_code_scanning": "code scanning results are required",
+	"workflows":              "workflows must pass before changes land",
+}
+
+// branchRule is an active repository rule, as returned by the rules API.
+type branchRule struct {
+	Type              string `json:"type"`
+	RulesetSource     string `json:"ruleset_source"`
+	RulesetSourceType string `json:"ruleset_source_type"`
+	RulesetID         int    `json:"ruleset_id"`
+}
+
+// signatureRule is the type of the rule requiring signed commits.
+const signatureRule = "required_signatures"
+
+// rulesetBranchPrefix prefixes the branch a publish is pushed to when the
+// rulesets of the target branch reject direct pushes.
+const rulesetBranchPrefix = "publish-directory/"
+
+// rulesetViolations queries the rulesets active on the target branch and
+// describes the rules that would reject a direct push by the action. Commits
+// signed with a configured key satisfy the rule requiring signatures.
+func rulesetViolations(cfg Config, repository string) []string {
+	var rules []branchRule
+	path := fmt.Sprintf("/repos/%s/rules/branches/%s", repository, url.PathEscape(cfg.Branch))
+	if err := githubRequest(cfg.GithubToken, "GET", path, nil, &rules); err != nil {
+		fmt.Printf("::warning title=Rulesets::%s\n", msg(messageRulesetsFailed, cfg.Branch, err))
+		return nil
+	}
+
+	signed := cfg.GPGPrivateKey != "" || cfg.SSHSigningKey != ""
+
+	var violations []string
+	for _, rule := range rules {
+		description, blocking := blockingRules[rule.Type]
+		if !blocking || (rule.Type == signatureRule && signed) {
+			continue
+		}
+
+		violations = append(violations, fmt.Sprintf("%s (%s, ruleset %d of %s)", description, rule.Type, rule.RulesetID, rule.RulesetSource))
+	}
+
+	return violations
+}
+
+// openPullRequest opens a pull request of the branch the publish was pushed to
+// into the target branch, or finds the one opened by an earlier run, and sets
+// the pull_request_url output.
+func openPullRequest(cfg Config, repository, title string) error {
+	var pullRequest struct {
+		HTMLURL string `json:"html_url"`
+	}
+
+	request := map[string]string{
+		"title": title,
+		"head":  cfg.PullRequestBranch,
+		"base":  cfg.Branch,
+		"body":  fmt.Sprintf("The rulesets of `%s` reject direct pushes, so this publish is proposed from `%s`.", cfg.Branch, cfg.PullRequestBranch),
+	}
+
+	err := githubRequest(cfg.GithubToken, "POST", fmt.Sprintf("/repos/%s/pulls", repository), request, &pullRequest)
+
+	var apiError *githubAPIError
+	if errors.As(err, &apiError) && apiError.StatusCode == http.StatusUnprocessableEntity {
+		owner, _, _ := strings.Cut(repository, "/")
+		query := url.Values{"head": {owner + ":" + cfg.PullRequestBranch}, "base": {cfg.Branch}, "state": {"open"}}
+
+		var existing []struct {
+			HTMLURL string `json:"html_url"`
+		}
+		if err := githubRequest(cfg.GithubToken, "GET", fmt.Sprintf("/repos/%s/pulls?%s", repository, query.Encode()), nil, &existing); err != nil {
+			return fmt.Errorf("failed to find pull request: %w", err)
+		}
+
+		if len(existing) == 0 {
+			return fmt.Errorf("failed to open pull request from '%s' into '%s': %w", cfg.PullRequestBranch, cfg.Branch, apiError)
+		}
+
+		fmt.Println(msg(messagePullRequestUpdated, existing[0].HTMLURL))
+		return setOutput("pull_request_url", existing[0].HTMLURL)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open pull request: %w", err)
+	}
+
+	fmt.Println(msg(messagePullRequestOpened, pullRequest.HTMLURL))
+	return setOutput("pull_request_url", pullRequest.HTMLURL)
+}
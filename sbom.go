@@ -0,0 +1,248 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// sbomFile describes a single published file in the SBOM.
+type sbomFile struct {
+	Path     string
+	Size     int64
+	SHA1     string
+	SHA256   string
+	Licenses []string
+}
+
+// collectSBOMFiles hashes every file in dir and detects its licenses.
+func collectSBOMFiles(dir string, exclude string) ([]sbomFile, error) {
+	var files []sbomFile
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if path == exclude {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		sha1Hash := sha1.New()
+		sha256Hash := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(sha1Hash, sha256Hash), file); err != nil {
+			return err
+		}
+
+		licenses, err := detectLicenses(path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, sbomFile{
+			Path:     filepath.ToSlash(relativePath),
+			Size:     info.Size(),
+			SHA1:     hex.EncodeToString(sha1Hash.Sum(nil)),
+			SHA256:   hex.EncodeToString(sha256Hash.Sum(nil)),
+			Licenses: licenses,
+		})
+		return nil
+	})
+
+	return files, err
+}
+
+// sbomPath returns the configured path of the SBOM on the branch, defaulting
+// to a name derived from its format.
+func sbomPath(cfg Config) string {
+	if cfg.SBOMPath != "" {
+		return filepath.ToSlash(cfg.SBOMPath)
+	}
+	return "sbom." + cfg.SBOM + ".json"
+}
+
+// sbomTime returns the creation time recorded in the SBOM, which has to be
+// the same for the same content so an SBOM does not change on every publish:
+// the configured commit date, otherwise the date of the source commit. The
+// zero time is returned when neither is known.
+func sbomTime(cfg Config) (time.Time, error) {
+	if reproducibleTime(cfg) {
+		return commitTime(cfg)
+	}
+
+	commit, err := sourceCommit(sourceFolders(cfg)[0].Path)
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return commit.Committer.When, nil
+}
+
+// writeSBOM generates an SBOM in the requested format describing the files in
+// dir and writes it to name, relative to dir. The document only depends on the
+// files, subject and creation time, so it is identical for identical content.
+func writeSBOM(dir, format, name, subject string, created time.Time) error {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+
+	files, err := collectSBOMFiles(dir, path)
+	if err != nil {
+		return err
+	}
+
+	var document any
+	switch format {
+	case "cyclonedx":
+		document = cycloneDXDocument(files, subject, created)
+	case "spdx":
+		document = spdxDocument(files, subject, created)
+	default:
+		return fmt.Errorf("unsupported SBOM format '%s'", format)
+	}
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func cycloneDXDocument(files []sbomFile, subject string, created time.Time) map[string]any {
+	components := make([]map[string]any, 0, len(files))
+	for _, file := range files {
+		component := map[string]any{
+			"type": "file",
+			"name": file.Path,
+			"hashes": []map[string]string{
+				{"alg": "SHA-1", "content": file.SHA1},
+				{"alg": "SHA-256", "content": file.SHA256},
+			},
+			"properties": []map[string]string{
+				{"name": "size", "value": strconv.FormatInt(file.Size, 10)},
+			},
+		}
+
+		if len(file.Licenses) > 0 {
+			licenses := make([]map[string]any, 0, len(file.Licenses))
+			for _, license := range file.Licenses {
+				licenses = append(licenses, map[string]any{"license": map[string]string{"id": license}})
+			}
+			component["licenses"] = licenses
+		}
+
+		components = append(components, component)
+	}
+
+	metadata := map[string]any{
+		"tools": map[string]any{
+			"components": []map[string]string{{"type": "application", "name": "publish-directory"}},
+		},
+		"component": map[string]string{"type": "application", "name": subject},
+	}
+	if !created.IsZero() {
+		metadata["timestamp"] = created.UTC().Format(time.RFC3339)
+	}
+
+	return map[string]any{
+		"bomFormat":    "CycloneDX",
+		"specVersion":  "1.5",
+		"serialNumber": "urn:uuid:" + contentUUID(files, subject),
+		"version":      1,
+		"metadata":     metadata,
+		"components":   components,
+	}
+}
+
+func spdxDocument(files []sbomFile, subject string, created time.Time) map[string]any {
+	// SPDX requires a creation time, the Unix epoch stands in for an unknown one.
+	if created.IsZero() {
+		created = time.Unix(0, 0)
+	}
+
+	spdxFiles := make([]map[string]any, 0, len(files))
+	for i, file := range files {
+		licenses := file.Licenses
+		if len(licenses) == 0 {
+			licenses = []string{"NOASSERTION"}
+		}
+
+		spdxFiles = append(spdxFiles, map[string]any{
+			"fileName": "./" + file.Path,
+			"SPDXID":   fmt.Sprintf("SPDXRef-File-%d", i+1),
+			"checksums": []map[string]string{
+				{"algorithm": "SHA1", "checksumValue": file.SHA1},
+				{"algorithm": "SHA256", "checksumValue": file.SHA256},
+			},
+			"licenseConcluded":   "NOASSERTION",
+			"licenseInfoInFiles": licenses,
+			"copyrightText":      "NOASSERTION",
+			"comment":            fmt.Sprintf("size: %d bytes", file.Size),
+		})
+	}
+
+	return map[string]any{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              subject,
+		"documentNamespace": "https://spdx.org/spdxdocs/publish-directory-" + contentUUID(files, subject),
+		"creationInfo": map[string]any{
+			"created":  created.UTC().Format(time.RFC3339),
+			"creators": []string{"Tool: publish-directory"},
+		},
+		"files": spdxFiles,
+	}
+}
+
+// contentUUID returns a name based (version 5 style) UUID derived from the
+// subject and the files, so the same content always gets the same UUID.
+func contentUUID(files []sbomFile, subject string) string {
+	hash := sha1.New()
+	fmt.Fprintln(hash, subject)
+	for _, file := range files {
+		fmt.Fprintln(hash, file.Path, file.SHA256)
+	}
+
+	b := hash.Sum(nil)[:16]
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// randomUUID returns a random (version 4) UUID.
+func randomUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// clamscanCommand scans the worktree with ClamAV, reporting only infected
+// files. clamscan exits with 1 when it finds infected files.
+const clamscanCommand = "clamscan --recursive --infected --no-summary --exclude-dir='^\\.git$' \"$PUBLISH_DIRECTORY_WORKTREE\""
+
+// scanContent runs the configured malware scanner over the prepared worktree
+// and blocks the publish when it reports a detection by exiting non-zero.
+func scanContent(cfg Config, dir string) error {
+	command := cfg.ScanCommand
+	if command == "" && cfg.ClamAV {
+		command = clamscanCommand
+	}
+
+	if command == "" {
+		return nil
+	}
+
+	fmt.Println(msg(messageScanning))
+
+	cmd := shellCommand(command)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "PUBLISH_DIRECTORY_WORKTREE="+dir)
+
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if cfg.ScanCommand == "" && exitErr.ExitCode() != 1 {
+			return fmt.Errorf("malware scan failed to run: %w", err)
+		}
+		return fmt.Errorf("malware scan reported detections, publish blocked")
+	}
+	if err != nil {
+		return fmt.Errorf("malware scan failed to run (%s): %w", strings.Fields(command)[0], err)
+	}
+
+	return nil
+}
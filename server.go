@@ -0,0 +1,321 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// maxWebhookSize is the largest webhook payload GitHub delivers.
+const maxWebhookSize = 25 * 1024 * 1024
+
+// publishJob is a publish performed by the server: a folder of a source
+// repository reference, published to a branch of the target repository.
+type publishJob struct {
+	Name       string `json:"name"`
+	Source     string `json:"source"`
+	Ref        string `json:"ref"`
+	Folder     string `json:"folder"`
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"`
+	// Schedule is a cron expression at which the job is re-run, so that the
+	// branch is continuously reconciled with its source.
+	Schedule string `json:"schedule,omitempty"`
+	// RepairDrift resets the branch to its last publish before publishing
+	// when commits were made to it outside of the action.
+	RepairDrift bool `json:"repair_drift,omitempty"`
+	// CommitMessage overrides the configured commit message.
+	CommitMessage string `json:"commit_message,omitempty"`
+}
+
+// sourceReference returns the fully qualified source reference of the job.
+func (j publishJob) sourceReference() plumbing.ReferenceName {
+	if strings.HasPrefix(j.Ref, "refs/") {
+		return plumbing.ReferenceName(j.Ref)
+	}
+	return plumbing.NewBranchReferenceName(j.Ref)
+}
+
+// serverConfig is the configuration file of the server mode.
+type serverConfig struct {
+	Jobs []publishJob `json:"jobs"`
+}
+
+// readServerConfig reads and validates the server configuration file.
+func readServerConfig(path string) (serverConfig, error) {
+	var config serverConfig
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
+
+	names := map[string]bool{}
+	for i, job := range config.Jobs {
+		switch {
+		case job.Name == "":
+			return config, fmt.Errorf("job %d is missing a name", i+1)
+		case names[job.Name]:
+			return config, fmt.Errorf("job '%s' is defined twice", job.Name)
+		case job.Source == "" || job.Ref == "" || job.Repository == "" || job.Branch == "":
+			return config, fmt.Errorf("job '%s' needs a source, ref, repository and branch", job.Name)
+		case job.Folder != "" && !filepath.IsLocal(filepath.FromSlash(job.Folder)):
+			return config, fmt.Errorf("folder of job '%s' must be relative to the source repository", job.Name)
+		}
+
+		if job.Schedule != "" {
+			if _, err := parseCron(job.Schedule); err != nil {
+				return config, fmt.Errorf("job '%s': %w", job.Name, err)
+			}
+		}
+		names[job.Name] = true
+	}
+
+	return config, nil
+}
+
+// cloneSource clones a reference of the source repository into a temporary
+// directory, removed by the returned cleanup function.
+func cloneSource(cfg Config, repository string, reference plumbing.ReferenceName) (string, func(), error) {
+	cfg.Repository = repository
+	if err := mintInstallationToken(&cfg, repository); err != nil {
+		return "", nil, err
+	}
+
+	_, url, auth, err := resolveRemote(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp(temporaryRoot(), "kontrolplane-publish-directory-source-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	_, err = git.PlainClone(dir, false, &git.CloneOptions{
+		URL:           url,
+		Auth:          auth,
+		ReferenceName: reference,
+		SingleBranch:  true,
+		Depth:         1,
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("failed to clone %s at %s: %w", repository, reference.Short(), err)
+	}
+
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// publishServer performs the configured publishes on request. Publishes run
+// one at a time, as the pipeline is not safe for concurrent use.
+type publishServer struct {
+	cfg  Config
+	jobs []publishJob
+	lock sync.Mutex
+
+	runs     map[string]*publishRun
+	runOrder []string
+	runsLock sync.Mutex
+}
+
+// run clones the source of the job and publishes its folder, returning the
+// published commit or an empty string when there was nothing to publish.
+func (s *publishServer) run(job publishJob) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	fmt.Println(msg(messageRunningJob, job.Name))
+
+	if job.Folder != "" && !filepath.IsLocal(filepath.FromSlash(job.Folder)) {
+		return "", fmt.Errorf("folder of job '%s' must be relative to the source repository", job.Name)
+	}
+
+	dir, cleanup, err := cloneSource(s.cfg, job.Source, job.sourceReference())
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	cfg := s.cfg
+	cfg.Folder = filepath.Join(dir, filepath.FromSlash(job.Folder))
+	cfg.Repository = job.Repository
+	cfg.Branch = job.Branch
+	cfg.AssumeYes = true
+	if job.CommitMessage != "" {
+		cfg.CommitMessage = job.CommitMessage
+	}
+
+	if err := mintInstallationToken(&cfg, job.Repository); err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(cfg.Folder); err != nil {
+		return "", fmt.Errorf("folder '%s' does not exist in %s at %s", job.Folder, job.Source, job.Ref)
+	}
+
+	// The clone is untrusted input, so links out of it are rejected as they
+	// are for the workspace.
+	if err := checkWorkspaceContainment(cfg.Folder, dir); err != nil {
+		return "", err
+	}
+
+	if job.RepairDrift {
+		repair := cfg
+		repair.Mode = modeRepair
+		if err := checkDrift(repair); err != nil {
+			fmt.Printf("::warning title=%s::%s\n", msg(messageDriftTitle), msg(messageJobRepairFailed, job.Name, err))
+		}
+	}
+
+	return publishCommit(cfg)
+}
+
+// publishCommit publishes the directory and returns the published commit, or
+// an empty string when there was nothing to publish. The publish records are
+// reset for every job, so they only describe its run and do not accumulate
+// over the lifetime of the server. Jobs run one at a time.
+func publishCommit(cfg Config) (string, error) {
+	publishRecords = nil
+	if err := publishDirectory(cfg, newDiagnostics(false)); err != nil {
+		return "", err
+	}
+
+	if len(publishRecords) == 0 {
+		return "", nil
+	}
+	return publishRecords[0].Published, nil
+}
+
+// schedule re-runs the job whenever its schedule fires.
+func (s *publishServer) schedule(job publishJob) {
+	schedule, _ := parseCron(job.Schedule)
+
+	for {
+		next, ok := schedule.next(time.Now())
+		if !ok {
+			fmt.Fprintln(os.Stderr, msg(messageScheduleNeverFires, job.Name))
+			return
+		}
+
+		time.Sleep(time.Until(next))
+		s.execute(s.track(job, "schedule"), job)
+	}
+}
+
+// serve runs the server mode, publishing the configured jobs when GitHub
+// delivers push or workflow_run webhooks for their source and whenever their
+// schedule fires.
+func serve(cfg Config) error {
+	config, err := readServerConfig(cfg.ServerConfig)
+	if err != nil {
+		return fmt.Errorf("failed to read server configuration: %w", err)
+	}
+
+	server := &publishServer{cfg: cfg, jobs: config.Jobs, runs: map[string]*publishRun{}}
+
+	for _, job := range config.Jobs {
+		if job.Schedule != "" {
+			go server.schedule(job)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhook", server.handleWebhook)
+
+	if cfg.APIToken != "" {
+		mux.HandleFunc("GET /publishes", server.authenticated(server.handleListPublishes))
+		mux.HandleFunc("POST /publishes", server.authenticated(server.handleTriggerPublish))
+		mux.HandleFunc("GET /publishes/{id}", server.authenticated(server.handleGetPublish))
+	}
+
+	// Publishes run in the background, so requests are answered quickly and
+	// slow clients cannot hold connections open.
+	httpServer := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	fmt.Println(msg(messageListening, cfg.Listen, len(config.Jobs)))
+	return httpServer.ListenAndServe()
+}
+
+// handleWebhook verifies a GitHub webhook delivery and starts the jobs whose
+// source it concerns.
+func (s *publishServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookSize))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.cfg.WebhookSecret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Hub-Signature-256"))) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Ref        string `json:"ref"`
+		Action     string `json:"action"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		WorkflowRun struct {
+			HeadBranch string `json:"head_branch"`
+			Conclusion string `json:"conclusion"`
+		} `json:"workflow_run"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	var ref plumbing.ReferenceName
+	switch r.Header.Get("X-GitHub-Event") {
+	case "push":
+		ref = plumbing.ReferenceName(payload.Ref)
+	case "workflow_run":
+		if payload.Action != "completed" || payload.WorkflowRun.Conclusion != "success" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		ref = plumbing.NewBranchReferenceName(payload.WorkflowRun.HeadBranch)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	started := 0
+	for _, job := range s.jobs {
+		if strings.EqualFold(job.Source, payload.Repository.FullName) && job.sourceReference() == ref {
+			s.start(job, "webhook")
+			started++
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, "started %d jobs\n", started)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"golang.org/x/crypto/ssh"
+)
+
+// gpgSignKey returns the configured GPG key to sign the publish commit with,
+// or nil when commits are not signed.
+func gpgSignKey(cfg Config) (*openpgp.Entity, error) {
+	if cfg.GPGPrivateKey == "" {
+		return nil, nil
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(cfg.GPGPrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GPG private key: %w", err)
+	}
+	if len(entities) == 0 || entities[0].PrivateKey == nil {
+		return nil, fmt.Errorf("gpg_private_key does not contain a private key")
+	}
+
+	entity := entities[0]
+	if err := entity.DecryptPrivateKeys([]byte(cfg.GPGPassphrase)); err != nil {
+		return nil, fmt.Errorf("failed to decrypt GPG private key: %w", err)
+	}
+
+	return entity, nil
+}
+
+// sshSignatureNamespace is the namespace git signs and verifies commits in.
+const sshSignatureNamespace = "git"
+
+// sshCommitSigner signs commits with an SSH key, producing the SSHSIG
+// signatures git writes with gpg.format set to ssh.
+type sshCommitSigner struct {
+	signer ssh.Signer
+}
+
+// sshSigner returns the configured SSH key to sign the publish commit with,
+// or nil when commits are not signed with an SSH key.
+func sshSigner(cfg Config) (git.Signer, error) {
+	if cfg.SSHSigningKey == "" {
+		return nil, nil
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(cfg.SSHSigningKey))
+	if _, ok := err.(*ssh.PassphraseMissingError); ok {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(cfg.SSHSigningKey), []byte(cfg.SSHSigningPassphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH signing key: %w", err)
+	}
+
+	return sshCommitSigner{signer: signer}, nil
+}
+
+func (s sshCommitSigner) Sign(message io.Reader) ([]byte, error) {
+	hash := sha512.New()
+	if _, err := io.Copy(hash, message); err != nil {
+		return nil, err
+	}
+
+	signed := ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{sshSignatureNamespace, "", "sha512", hash.Sum(nil)})
+
+	// RSA keys sign with SHA-512, as the SHA-1 default is rejected by git.
+	var signature *ssh.Signature
+	var err error
+	if algorithmSigner, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, append([]byte("SSHSIG"), signed...), ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = s.signer.Sign(rand.Reader, append([]byte("SSHSIG"), signed...))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with SSH key: %w", err)
+	}
+
+	blob := ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}{1, s.signer.PublicKey().Marshal(), sshSignatureNamespace, "", "sha512", ssh.Marshal(signature)})
+
+	encoded := base64.StdEncoding.EncodeToString(append([]byte("SSHSIG"), blob...))
+
+	var armored strings.Builder
+	armored.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		armored.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	armored.WriteString(encoded + "\n-----END SSH SIGNATURE-----\n")
+
+	return []byte(armored.String()), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// githubFileSizeLimit is the size above which GitHub rejects pushed files.
+	githubFileSizeLimit = 100 * 1024 * 1024
+	// githubFileSizeWarning is the size above which GitHub warns about files.
+	githubFileSizeWarning = 50 * 1024 * 1024
+)
+
+// findLargeFiles returns the slash separated paths, relative to dir, of all
+// files larger than threshold bytes.
+func findLargeFiles(dir string, threshold int64) ([]string, error) {
+	var paths []string
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.Size() <= threshold {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		paths = append(paths, filepath.ToSlash(relativePath))
+		return nil
+	})
+
+	return paths, err
+}
+
+// lfsPatternsForPaths turns paths into LFS track patterns anchored at the root
+// of the published tree.
+func lfsPatternsForPaths(paths []string) []string {
+	patterns := make([]string, 0, len(paths))
+	for _, path := range paths {
+		patterns = append(patterns, "/"+strings.ReplaceAll(path, " ", "?"))
+	}
+	return patterns
+}
+
+// checkFileSizes fails when files in dir exceed GitHub's hard file size limit
+// and warns about files exceeding the recommended size.
+func checkFileSizes(dir string) error {
+	warnings, err := findLargeFiles(dir, githubFileSizeWarning)
+	if err != nil {
+		return err
+	}
+
+	var oversized []string
+	for _, path := range warnings {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(path)))
+		if err != nil {
+			return err
+		}
+
+		if info.Size() > githubFileSizeLimit {
+			oversized = append(oversized, path)
+			continue
+		}
+
+		fmt.Println(msg(messageLargeFile, path))
+	}
+
+	if len(oversized) > 0 {
+		return fmt.Errorf("files exceed GitHub's 100 MB limit and would be rejected: %s (set lfs_oversized: true to store them with Git LFS)", strings.Join(oversized, ", "))
+	}
+
+	return nil
+}
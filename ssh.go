@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// githubKnownHosts is the published SSH host key of github.com, used when no
+// known hosts are configured.
+const githubKnownHosts = "github.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl\n"
+
+// sshAuth returns the public key authentication for the configured deploy
+// key, verifying host against the configured known hosts. Without configured
+// known hosts the published host key of github.com is trusted, or for other
+// hosts the known hosts of the system.
+func sshAuth(cfg Config, host string) (*ssh.PublicKeys, error) {
+	auth, err := ssh.NewPublicKeys("git", []byte(cfg.SSHKey), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key: %w", err)
+	}
+
+	knownHosts := cfg.SSHKnownHosts
+	if knownHosts == "" && host == "github.com" {
+		knownHosts = githubKnownHosts
+	}
+
+	var files []string
+	if knownHosts != "" {
+		file, err := os.CreateTemp(temporaryRoot(), "kontrolplane-publish-directory-known-hosts-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to write known hosts: %w", err)
+		}
+		defer os.Remove(file.Name())
+
+		_, err = file.WriteString(knownHosts)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write known hosts: %w", err)
+		}
+
+		files = append(files, file.Name())
+	}
+
+	db, err := ssh.NewKnownHostsDb(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known hosts: %w", err)
+	}
+
+	auth.HostKeyCallback = db.HostKeyCallback()
+	auth.HostKeyAlgorithms = db.HostKeyAlgorithms(host + ":22")
+	return auth, nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Phases a target goes through, in order, as recorded in the state file.
+const (
+	phasePrepared  = "prepared"
+	phaseCommitted = "committed"
+	phasePushed    = "pushed"
+)
+
+// publishState is the persisted progress of a publish, allowing a re-run after
+// a crash to resume instead of preparing the tree again.
+type publishState struct {
+	Targets map[string]*targetState `json:"targets"`
+}
+
+// targetState is the persisted progress of publishing to a single target.
+type targetState struct {
+	Directory  string      `json:"directory,omitempty"`
+	LFSStorage string      `json:"lfs_storage,omitempty"`
+	Phase      string      `json:"phase,omitempty"`
+	Tree       string      `json:"tree,omitempty"`
+	Commit     string      `json:"commit,omitempty"`
+	Changes    changeStats `json:"changes"`
+	// Orphan is set when the commit replaces the history of the branch and
+	// has to be force-pushed.
+	Orphan bool `json:"orphan,omitempty"`
+}
+
+// resumable reports whether the prepared repository of an earlier run can be
+// picked up again.
+func (s *targetState) resumable() bool {
+	if s.Phase != phasePrepared && s.Phase != phaseCommitted {
+		return false
+	}
+
+	_, err := os.Stat(filepath.Join(s.Directory, ".git"))
+	return err == nil
+}
+
+func loadState(path string) (*publishState, error) {
+	state := &publishState{Targets: map[string]*targetState{}}
+	if path == "" {
+		return state, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file '%s': %w", path, err)
+	}
+
+	if state.Targets == nil {
+		state.Targets = map[string]*targetState{}
+	}
+
+	return state, nil
+}
+
+// loadTargetState returns the persisted state of a single target, or an empty
+// state when there is none or no state file is configured.
+func loadTargetState(path, key string) (*targetState, error) {
+	state, err := loadState(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if target, ok := state.Targets[key]; ok {
+		return target, nil
+	}
+
+	return &targetState{}, nil
+}
+
+// saveTargetState persists the state of a single target, leaving the other
+// targets in the state file untouched.
+func saveTargetState(path, key string, target *targetState) error {
+	if path == "" {
+		return nil
+	}
+
+	state, err := loadState(path)
+	if err != nil {
+		return err
+	}
+	state.Targets[key] = target
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporaryPath := path + ".tmp"
+	if err := os.WriteFile(temporaryPath, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(temporaryPath, path)
+}
+
+// clearState removes the state file and the work directories it references
+// once every target has been published.
+func clearState(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if err := os.RemoveAll(workDirectoryRoot(path)); err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func workDirectoryRoot(stateFile string) string {
+	return stateFile + ".work"
+}
+
+// createWorkDirectories creates the directories the repository is prepared in
+// and the LFS objects are stored in. Without a state file these are temporary
+// and removed by the returned cleanup function; with one they are kept next to
+// the state file so a later run can resume.
+func createWorkDirectories(stateFile, key string) (string, string, func(), error) {
+	if stateFile == "" {
+		workDirectory, err := os.MkdirTemp(temporaryRoot(), "kontrolplane-publish-directory-*")
+		if err != nil {
+			return "", "", nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+
+		lfsStorage, err := os.MkdirTemp(temporaryRoot(), "kontrolplane-publish-directory-lfs-*")
+		if err != nil {
+			os.RemoveAll(workDirectory)
+			return "", "", nil, fmt.Errorf("failed to create LFS storage directory: %w", err)
+		}
+
+		return workDirectory, lfsStorage, func() {
+			os.RemoveAll(workDirectory)
+			os.RemoveAll(lfsStorage)
+		}, nil
+	}
+
+	base := filepath.Join(workDirectoryRoot(stateFile), strings.NewReplacer("/", "_", "@", "_").Replace(key))
+	if err := os.RemoveAll(base); err != nil {
+		return "", "", nil, err
+	}
+
+	workDirectory := filepath.Join(base, "repository")
+	lfsStorage := filepath.Join(base, "lfs")
+	for _, dir := range []string{workDirectory, lfsStorage} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", "", nil, fmt.Errorf("failed to create work directory: %w", err)
+		}
+	}
+
+	return workDirectory, lfsStorage, func() {}, nil
+}
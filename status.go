@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const githubStatusURL = "https://www.githubstatus.com/api/v2/components.json"
+
+// githubStatusComponents is the subset of the GitHub status page components
+// response needed to determine whether Git operations are degraded.
+type githubStatusComponents struct {
+	Components []struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	} `json:"components"`
+}
+
+// githubGitIncident queries the GitHub status page and returns a description
+// of the problem when Git operations are not fully operational. An empty
+// string is returned when there is no incident.
+func githubGitIncident() (string, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	response, err := client.Get(githubStatusURL)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status page returned %s", response.Status)
+	}
+
+	var status githubStatusComponents
+	if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+		return "", err
+	}
+
+	for _, component := range status.Components {
+		if component.Name != "Git Operations" || component.Status == "operational" {
+			continue
+		}
+
+		return fmt.Sprintf("GitHub reports Git Operations as %s", strings.ReplaceAll(component.Status, "_", " ")), nil
+	}
+
+	return "", nil
+}
+
+// checksGithubStatus reports whether failures are checked against the GitHub
+// status page, which only covers publishes to github.com.
+func checksGithubStatus(cfg Config) bool {
+	if !cfg.CheckStatus || remoteProvider(cfg) != providerGithub {
+		return false
+	}
+
+	remote := cfg.RemoteURL
+	if remote == "" && strings.Contains(cfg.Repository, "://") {
+		remote = cfg.Repository
+	}
+	if remote == "" {
+		return githubHost() == "github.com"
+	}
+
+	// SSH remotes may use the scp-like syntax, user@host:path.
+	if !strings.Contains(remote, "://") {
+		if _, after, found := strings.Cut(remote, "@"); found {
+			remote = after
+		}
+		host, _, _ := strings.Cut(remote, ":")
+		return strings.EqualFold(host, "github.com")
+	}
+
+	parsed, err := url.Parse(remote)
+	return err == nil && strings.EqualFold(parsed.Hostname(), "github.com")
+}
+
+// githubIncidentError is a failure during an active GitHub incident affecting
+// Git operations.
+type githubIncidentError struct {
+	Incident string
+	Err      error
+}
+
+func (e *githubIncidentError) Error() string {
+	return fmt.Sprintf("%s, see https://www.githubstatus.com: %v", e.Incident, e.Err)
+}
+
+func (e *githubIncidentError) Unwrap() error {
+	return e.Err
+}
+
+// incidentError wraps err with the active GitHub incident affecting Git
+// operations, if any, so the failure is not mistaken for a configuration issue.
+func incidentError(err error) error {
+	var incidentErr *githubIncidentError
+	if errors.As(err, &incidentErr) {
+		return err
+	}
+
+	incident, statusErr := githubGitIncident()
+	if statusErr != nil || incident == "" {
+		return err
+	}
+
+	return &githubIncidentError{Incident: incident, Err: err}
+}
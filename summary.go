@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// changeStats summarizes the changes a publish commits to the branch.
+type changeStats struct {
+	Added    int `json:"added,omitempty"`
+	Modified int `json:"modified,omitempty"`
+	Deleted  int `json:"deleted,omitempty"`
+	// Size is the total size in bytes of the published files.
+	Size int64 `json:"size,omitempty"`
+}
+
+// files returns the number of files changed.
+func (s changeStats) files() int {
+	return s.Added + s.Modified + s.Deleted
+}
+
+// statusStats counts the staged changes in status and measures the size of the
+// files in dir.
+func statusStats(status git.Status, dir string) (changeStats, error) {
+	var stats changeStats
+	for _, fileStatus := range status {
+		switch fileStatus.Staging {
+		case git.Unmodified, git.Untracked:
+		case git.Added:
+			stats.Added++
+		case git.Deleted:
+			stats.Deleted++
+		default:
+			stats.Modified++
+		}
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if info.Mode().IsRegular() {
+			stats.Size += info.Size()
+		}
+		return nil
+	})
+
+	return stats, err
+}
+
+// writeStepSummary appends a markdown report of the publish to the file
+// referenced by GITHUB_STEP_SUMMARY. It is a no-op when running outside of
+// GitHub Actions.
+func writeStepSummary(repository, branch, commit string, pushed bool, stats changeStats) error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if path == "" {
+		return nil
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s\n\n", msg(messageSummaryTitle, branch, repository))
+	if !pushed {
+		fmt.Fprintf(&builder, "%s\n\n", msg(messageSummaryUpToDate))
+	}
+	builder.WriteString("| | |\n| --- | --- |\n")
+	if commit != "" {
+		fmt.Fprintf(&builder, "| %s | [`%s`](%s/%s/commit/%s) |\n", msg(messageSummaryCommit), commit[:min(len(commit), 7)], githubServerURL(), repository, commit)
+	}
+	fmt.Fprintf(&builder, "| %s | %d |\n", msg(messageSummaryAdded), stats.Added)
+	fmt.Fprintf(&builder, "| %s | %d |\n", msg(messageSummaryModified), stats.Modified)
+	fmt.Fprintf(&builder, "| %s | %d |\n", msg(messageSummaryDeleted), stats.Deleted)
+	fmt.Fprintf(&builder, "| %s | %s |\n\n", msg(messageSummarySize), formatSize(stats.Size))
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(builder.String())
+	return err
+}
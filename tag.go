@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Kinds of ref the publish commit is pushed as.
+const (
+	refTypeBranch = "branch"
+	refTypeTag    = "tag"
+)
+
+// tagName returns the name of the tag to create for the publish, with {branch}
+// and the run context placeholders expanded.
+func tagName(cfg Config) string {
+	return expandTemplate(strings.ReplaceAll(cfg.Tag, "{branch}", cfg.Branch), time.Now())
+}
+
+// pushTag creates the configured tag pointing at commit and pushes it,
+// reporting whether it was pushed. An existing tag on the remote is never
+// moved.
+func pushTag(cfg Config, repo *git.Repository, auth transport.AuthMethod, commit plumbing.Hash) (bool, error) {
+	name := tagName(cfg)
+
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return false, err
+	}
+
+	// Pushing fast-forwards existing tags, where git would refuse to move them.
+	// Annotated tags are advertised with the tag object, and with the commit
+	// they point at under the peeled name.
+	references, err := remote.List(&git.ListOptions{Auth: auth, PeelingOption: git.AppendPeeled})
+	if err != nil {
+		return false, fmt.Errorf("failed to list remote tags: %w", err)
+	}
+	tagRef := plumbing.NewTagReferenceName(name)
+	peeledRef := plumbing.ReferenceName(tagRef.String() + "^{}")
+	exists, tagged := false, false
+	for _, existing := range references {
+		if existing.Name() != tagRef && existing.Name() != peeledRef {
+			continue
+		}
+		exists = true
+		tagged = tagged || existing.Hash() == commit
+	}
+	if tagged {
+		return false, setOutput("tag", name)
+	}
+	if exists {
+		return false, fmt.Errorf("tag '%s' already exists on the remote", name)
+	}
+
+	reference, err := createTag(cfg, repo, name, commit)
+	if err != nil {
+		return false, fmt.Errorf("failed to create tag '%s': %w", name, err)
+	}
+
+	fmt.Println(msg(messagePushingTag, name))
+
+	refSpec := config.RefSpec(fmt.Sprintf("%s:%s", reference.Name(), reference.Name()))
+	err = retryPush(cfg, func() error {
+		return repo.Push(&git.PushOptions{
+			RemoteName: "origin",
+			Auth:       auth,
+			RefSpecs:   []config.RefSpec{refSpec},
+			Progress:   progressWriter(),
+		})
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return false, fmt.Errorf("failed to push tag '%s': %w", name, err)
+	}
+
+	return err == nil, setOutput("tag", name)
+}
+
+// createTag creates the tag locally. With a tag message the tag is annotated,
+// tagged by the committer and, when configured, signed with the GPG key;
+// otherwise it is a lightweight reference to commit.
+func createTag(cfg Config, repo *git.Repository, name string, commit plumbing.Hash) (*plumbing.Reference, error) {
+	if cfg.TagMessage == "" {
+		if cfg.SignTag {
+			return nil, fmt.Errorf("sign_tag requires tag_message")
+		}
+
+		reference := plumbing.NewHashReference(plumbing.NewTagReferenceName(name), commit)
+		return reference, repo.Storer.SetReference(reference)
+	}
+
+	when, err := commitTime(cfg)
+	if err != nil {
+		return nil, err
+	}
+	_, tagger := commitSignatures(cfg, when)
+
+	options := &git.CreateTagOptions{
+		Tagger:  tagger,
+		Message: expandTemplate(strings.ReplaceAll(cfg.TagMessage, "{branch}", cfg.Branch), when),
+	}
+
+	if cfg.SignTag {
+		if cfg.GPGPrivateKey == "" {
+			return nil, fmt.Errorf("sign_tag requires gpg_private_key")
+		}
+
+		options.SignKey, err = gpgSignKey(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return repo.CreateTag(name, commit, options)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	exitFailure        = 1
+	exitPartialFailure = 2
+)
+
+// target is a single repository and branch to publish to.
+type target struct {
+	Repository string
+	Branch     string
+}
+
+func (t target) String() string {
+	if t.Repository == "" {
+		return t.Branch
+	}
+	return t.Repository + "@" + t.Branch
+}
+
+// parseTargets parses a newline or comma separated list of targets, each
+// either a branch of the configured repository or an 'owner/repository@branch'.
+func parseTargets(input string) ([]target, error) {
+	var targets []target
+
+	for _, item := range splitList(input) {
+		repository, branch, found := strings.Cut(item, "@")
+		if !found {
+			repository, branch = "", item
+		}
+
+		if branch == "" {
+			return nil, fmt.Errorf("target '%s' is missing a branch", item)
+		}
+
+		targets = append(targets, target{Repository: repository, Branch: branch})
+	}
+
+	return targets, nil
+}
+
+// publishTargets publishes the directory to every configured target, carrying
+// on past individual failures, and returns the process exit code.
+func publishTargets(cfg Config, targets []target) int {
+	var succeeded, failed []string
+
+	state, err := loadState(cfg.StateFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, msg(messageLoadStateFailed, err))
+		return exitFailure
+	}
+
+	for _, t := range targets {
+		if t.Repository == "" {
+			repository, err := resolveRepository(cfg)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, msg(messageResolveRepositoryFailed, t, err))
+				failed = append(failed, t.String())
+				continue
+			}
+			t.Repository = repository
+		}
+
+		if targetState, ok := state.Targets[t.String()]; ok && targetState.Phase == phasePushed {
+			fmt.Println(msg(messageSkippingTarget, t))
+			succeeded = append(succeeded, t.String())
+			continue
+		}
+
+		fmt.Println(msg(messagePublishingTarget, t))
+
+		targetConfig := cfg
+		if t.Repository != "" {
+			targetConfig.Repository = t.Repository
+		}
+		targetConfig.Branch = t.Branch
+
+		if targetConfig.DiagnosticsPath != "" {
+			targetConfig.DiagnosticsPath = filepath.Join(cfg.DiagnosticsPath, strings.NewReplacer("/", "_", "@", "_").Replace(t.String()))
+		}
+
+		diag := newDiagnostics(targetConfig.DiagnosticsPath != "")
+		if err := publishDirectory(targetConfig, diag); err != nil {
+			reportError(targetConfig, diag, err)
+			failed = append(failed, t.String())
+			continue
+		}
+
+		succeeded = append(succeeded, t.String())
+	}
+
+	fmt.Println(msg(messageSummary))
+	for _, t := range succeeded {
+		fmt.Println(msg(messageSummarySucceeded, t))
+	}
+	for _, t := range failed {
+		fmt.Println(msg(messageSummaryFailed, t))
+	}
+
+	for name, value := range map[string]string{
+		"succeeded_targets": strings.Join(succeeded, "\n"),
+		"failed_targets":    strings.Join(failed, "\n"),
+	} {
+		if err := setOutput(name, value); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageSetOutputFailed, name, err))
+		}
+	}
+
+	switch {
+	case len(failed) == 0 && cfg.DryRun:
+		return 0
+	case len(failed) == 0:
+		if err := clearState(cfg.StateFile); err != nil {
+			fmt.Fprintln(os.Stderr, msg(messageRemoveStateFailed, err))
+		}
+		return 0
+	case len(succeeded) == 0:
+		return exitFailure
+	default:
+		return exitPartialFailure
+	}
+}
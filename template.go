@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// expandTemplate replaces the run context placeholders in input, such as
+// {sha}, {run_id} and {pr_number}, with their values from the GitHub Actions
+// environment.
+func expandTemplate(input string, now time.Time) string {
+	if !strings.Contains(input, "{") {
+		return input
+	}
+
+	sha := os.Getenv("GITHUB_SHA")
+	shortSHA := sha
+	if len(shortSHA) > 7 {
+		shortSHA = shortSHA[:7]
+	}
+
+	prNumber := ""
+	if number := githubEventNumber(); number != 0 && isPullRequestEvent() {
+		prNumber = strconv.Itoa(number)
+	}
+
+	return strings.NewReplacer(
+		"{sha}", sha,
+		"{short_sha}", shortSHA,
+		"{run_id}", os.Getenv("GITHUB_RUN_ID"),
+		"{run_number}", os.Getenv("GITHUB_RUN_NUMBER"),
+		"{run_attempt}", os.Getenv("GITHUB_RUN_ATTEMPT"),
+		"{actor}", os.Getenv("GITHUB_ACTOR"),
+		"{ref}", os.Getenv("GITHUB_REF"),
+		"{ref_name}", os.Getenv("GITHUB_REF_NAME"),
+		"{repository}", os.Getenv("GITHUB_REPOSITORY"),
+		"{pr_number}", prNumber,
+		"{date}", now.UTC().Format("2006-01-02"),
+		"{timestamp}", now.UTC().Format("20060102T150405Z"),
+	).Replace(input)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// isExpiredCredential reports whether err is the remote rejecting the
+// credentials, which for short-lived tokens such as GitHub App installation
+// tokens usually means they expired during a long publish.
+func isExpiredCredential(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired)
+}
+
+// mintInstallationToken replaces the token in cfg with a new installation token
+// for repository when authenticating as a GitHub App. Installation tokens
+// expire after an hour, so the server and operator modes mint one per job
+// instead of relying on the token minted at startup.
+func mintInstallationToken(cfg *Config, repository string) error {
+	if cfg.AppID == "" {
+		return nil
+	}
+
+	token, err := installationToken(*cfg, repository)
+	if err != nil {
+		return err
+	}
+
+	cfg.GithubToken = token
+	return nil
+}
+
+// refreshToken runs the token refresh command, or mints a new installation
+// token when authenticating as a GitHub App, and replaces the token in cfg and
+// auth with the new token.
+func refreshToken(cfg *Config, auth *http.BasicAuth) error {
+	fmt.Println(msg(messageRefreshingToken))
+
+	if cfg.TokenRefreshCommand == "" {
+		repository, err := resolveRepository(*cfg)
+		if err != nil {
+			return err
+		}
+
+		token, err := installationToken(*cfg, repository)
+		if err != nil {
+			return err
+		}
+
+		cfg.GithubToken = token
+		auth.Password = token
+		return nil
+	}
+
+	cmd := shellCommand(cfg.TokenRefreshCommand)
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("token refresh command failed: %w", err)
+	}
+
+	token := strings.TrimSpace(string(output))
+	if token == "" {
+		return fmt.Errorf("token refresh command printed no token")
+	}
+
+	fmt.Printf("::add-mask::%s\n", token)
+	cfg.GithubToken = token
+	auth.Password = token
+	return nil
+}
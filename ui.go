@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ANSI colors used by the terminal renderer.
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+	colorCyan   = "36"
+)
+
+// useColor reports whether output should be decorated, which is only the case
+// outside CI when the log is written to a terminal and NO_COLOR is not set.
+func useColor() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("CI") != "" || os.Getenv("GITHUB_ACTIONS") != "" {
+		return false
+	}
+	return isTerminal(os.Stdout)
+}
+
+// jsonOutput receives the outputs as JSON lines when the --json flag is given.
+var jsonOutput io.Writer
+
+// enableJSONOutput writes the outputs to stdout as JSON lines, one object with
+// the name and value of an output per line, for scripts driving the CLI. The
+// log moves to stderr to keep stdout parseable.
+func enableJSONOutput() {
+	jsonOutput = os.Stdout
+	os.Stdout = os.Stderr
+}
+
+// writeJSONOutput writes an output as a JSON line when JSON output is enabled.
+func writeJSONOutput(name, value string) error {
+	if jsonOutput == nil {
+		return nil
+	}
+	return json.NewEncoder(jsonOutput).Encode(map[string]string{"name": name, "value": value})
+}
+
+// colorize wraps text in the ANSI color when output is decorated.
+func colorize(color, text string) string {
+	if !useColor() {
+		return text
+	}
+	return "\x1b[" + color + "m" + text + "\x1b[0m"
+}
+
+// statusColors maps git status codes to the color they are rendered in.
+var statusColors = map[byte]string{
+	'A': colorGreen,
+	'?': colorGreen,
+	'M': colorYellow,
+	'R': colorYellow,
+	'C': colorYellow,
+	'D': colorRed,
+}
+
+// spinner shows an animated progress indicator next to message on terminals
+// and prints the message once otherwise. The returned function stops it.
+func spinner(message string) func() {
+	if !useColor() {
+		fmt.Println(message)
+		return func() {}
+	}
+
+	frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+
+		for i := 0; ; i++ {
+			fmt.Printf("\r%s %s", colorize(colorCyan, frames[i%len(frames)]), message)
+
+			select {
+			case <-done:
+				fmt.Printf("\r%s %s\n", colorize(colorGreen, "✓"), message)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// progressWriter returns where git progress is written: stdout in CI, where
+// the log is the only feedback, and nowhere on terminals, where a spinner is
+// shown instead.
+func progressWriter() io.Writer {
+	if useColor() {
+		return nil
+	}
+	return os.Stdout
+}
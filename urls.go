@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// publishedFiles returns the slash separated paths of all files in dir.
+func publishedFiles(dir string) ([]string, error) {
+	var files []string
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, filepath.ToSlash(relativePath))
+		return nil
+	})
+
+	sort.Strings(files)
+	return files, err
+}
+
+// publicURLs maps every published file to its public URL below baseURL. The
+// base URL may contain {branch} and the run context placeholders.
+func publicURLs(files []string, baseURL, repository, branch string) map[string]string {
+	base := expandTemplate(strings.NewReplacer("{branch}", branch, "{repository}", repository).Replace(baseURL), time.Now())
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+
+	urls := make(map[string]string, len(files))
+	for _, file := range files {
+		segments := strings.Split(file, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		urls[file] = base + strings.Join(segments, "/")
+	}
+
+	return urls
+}
+
+// writeURLManifest publishes the public URLs of the files in dir as a step
+// output and, when path is set, as a JSON manifest at path.
+func writeURLManifest(cfg Config, dir, repository string) error {
+	files, err := publishedFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	urls := publicURLs(files, cfg.BaseURL, repository, cfg.Branch)
+
+	if cfg.URLManifest != "" {
+		data, err := json.MarshalIndent(urls, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(cfg.URLManifest, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+
+		fmt.Println(msg(messageURLManifest, len(urls), cfg.URLManifest))
+	}
+
+	list := make([]string, 0, len(files))
+	for _, file := range files {
+		list = append(list, urls[file])
+	}
+
+	return setOutput("urls", strings.Join(list, "\n"))
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// verifyBranch compares the files in the source folder with the content of the
+// remote branch by their blob hashes, reporting files that differ, are missing
+// from the branch or only exist on it. Files generated by the action and LFS
+// pointers, compared by their oid, are taken into account.
+func verifyBranch(cfg Config) error {
+	_, url, auth, err := resolveRemote(cfg)
+	if err != nil {
+		return err
+	}
+
+	repo, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
+		URL:           url,
+		Auth:          auth,
+		ReferenceName: plumbing.NewBranchReferenceName(cfg.Branch),
+		SingleBranch:  true,
+		Depth:         1,
+		NoCheckout:    true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch branch '%s': %w", cfg.Branch, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve branch tip: %w", err)
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+
+	content := contentPath(cfg)
+	if content != "" {
+		tree, err = tree.Tree(content)
+		if err != nil {
+			return fmt.Errorf("target directory '%s' does not exist on branch '%s': %w", content, cfg.Branch, err)
+		}
+	}
+
+	published := map[string]*object.File{}
+	err = tree.Files().ForEach(func(file *object.File) error {
+		published[file.Name] = file
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	local, err := sourceFiles(cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.KeepFiles != "" {
+		removed := includeFilter(splitList(cfg.KeepFiles))
+		for name := range published {
+			if !removed(splitPath(name), false) {
+				delete(published, name)
+				delete(local, name)
+			}
+		}
+	}
+
+	generated := map[string]bool{}
+	if cfg.CNAME != "" {
+		delete(local, cnameFile)
+		generated[cnameFile] = true
+	}
+	if cfg.NoJekyll && content == "" {
+		generated[noJekyllFile] = true
+	}
+	for _, path := range generatedPaths(cfg) {
+		if content != "" {
+			path, _ = strings.CutPrefix(path, content+"/")
+		}
+		generated[path] = true
+	}
+
+	var mismatches []string
+	for name, path := range local {
+		file, ok := published[name]
+		if !ok {
+			mismatches = append(mismatches, msg(messageVerifyMissing, name))
+			continue
+		}
+
+		matches, err := fileMatchesBlob(path, file)
+		if err != nil {
+			return fmt.Errorf("failed to compare '%s': %w", name, err)
+		}
+
+		if !matches {
+			mismatches = append(mismatches, msg(messageVerifyDiffers, name))
+		}
+	}
+
+	for name := range published {
+		if _, ok := local[name]; !ok && !generated[name] && name != ".gitattributes" && cfg.Clean {
+			mismatches = append(mismatches, msg(messageVerifyOnlyOnBranch, name))
+		}
+	}
+
+	sort.Strings(mismatches)
+	for _, mismatch := range mismatches {
+		fmt.Println(mismatch)
+	}
+
+	if err := setOutput("verified", fmt.Sprintf("%t", len(mismatches) == 0)); err != nil {
+		return err
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("branch '%s' at %s does not match folder '%s': %d mismatches", cfg.Branch, commit.Hash, cfg.Folder, len(mismatches))
+	}
+
+	fmt.Println(msg(messageVerified, cfg.Branch, commit.Hash, cfg.Folder, len(local)))
+	return nil
+}
+
+// fileMatchesBlob reports whether the local file has the content of the
+// published blob, or of the LFS object it points to.
+func fileMatchesBlob(path string, file *object.File) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	if plumbing.ComputeHash(plumbing.BlobObject, content) == file.Hash {
+		return true, nil
+	}
+
+	if file.Size >= 1024 {
+		return false, nil
+	}
+
+	pointer, err := file.Contents()
+	if err != nil {
+		return false, err
+	}
+
+	oid, ok := lfsPointerOid(pointer)
+	if !ok {
+		return false, nil
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]) == oid, nil
+}
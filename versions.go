@@ -0,0 +1,319 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	versionsSchemaMike = "mike"
+	versionsSchemaList = "list"
+)
+
+// versionEntry describes a published version in the versions manifest, in
+// the format of mike, which documentation version switchers understand.
+type versionEntry struct {
+	Version string   `json:"version"`
+	Title   string   `json:"title"`
+	Aliases []string `json:"aliases"`
+}
+
+// contentPath returns the slash separated directory of the branch the folder
+// is published to: the target directory and, for versioned publishes, the
+// version below it. It is empty for the root of the branch.
+func contentPath(cfg Config) string {
+	if cfg.TargetDir == "" && cfg.Version == "" {
+		return ""
+	}
+	return path.Join(filepath.ToSlash(cfg.TargetDir), cfg.Version)
+}
+
+// isVersionName reports whether name can be the directory of a version or an
+// alias: a single path component within the target directory.
+func isVersionName(name string) bool {
+	return name != "." && !strings.ContainsAny(name, "/\\") && isBranchPath(name)
+}
+
+// updateVersions adds the published version to the versions manifest in the
+// target directory below dir, or updates its entry when the version was
+// published before. New versions are listed first.
+func updateVersions(cfg Config, dir string) error {
+	file := filepath.Join(dir, filepath.FromSlash(cfg.TargetDir), filepath.FromSlash(cfg.VersionsFile))
+
+	entries, err := readVersions(file, cfg.VersionsSchema)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", cfg.VersionsFile, err)
+	}
+
+	title := cfg.VersionTitle
+	if title == "" {
+		title = cfg.Version
+	}
+
+	aliases := splitList(cfg.Aliases)
+
+	entry := versionEntry{Version: cfg.Version, Title: title, Aliases: []string{}}
+	updated := false
+	for i := range entries {
+		if slices.Contains(aliases, entries[i].Version) {
+			return fmt.Errorf("alias '%s' is the name of a published version", entries[i].Version)
+		}
+
+		if entries[i].Version == cfg.Version {
+			entry.Aliases = entries[i].Aliases
+			entries[i] = entry
+			updated = true
+			continue
+		}
+
+		// An alias points at a single version, so it moves to the published
+		// version.
+		entries[i].Aliases = slices.DeleteFunc(entries[i].Aliases, func(alias string) bool {
+			return slices.Contains(aliases, alias)
+		})
+	}
+	if !updated {
+		entries = append([]versionEntry{entry}, entries...)
+	}
+
+	for i := range entries {
+		if entries[i].Version != cfg.Version {
+			continue
+		}
+
+		for _, alias := range aliases {
+			if !slices.Contains(entries[i].Aliases, alias) {
+				entries[i].Aliases = append(entries[i].Aliases, alias)
+			}
+		}
+	}
+
+	if cfg.KeepVersions != "" {
+		entries, err = retainVersions(cfg, dir, entries)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeVersions(file, cfg.VersionsSchema, entries)
+}
+
+// retainVersions applies the retention policy to the versions: either the
+// number of most recently published versions to keep, or version constraints
+// such as '>=2.0 <4' that kept versions satisfy. The directories of the other
+// versions and of their aliases are removed from dir. The published version is
+// always kept.
+func retainVersions(cfg Config, dir string, entries []versionEntry) ([]versionEntry, error) {
+	keep, err := versionRetention(cfg.KeepVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []versionEntry
+	for i, entry := range entries {
+		if entry.Version == cfg.Version || keep(i, entry.Version) {
+			kept = append(kept, entry)
+			continue
+		}
+
+		if !isVersionName(entry.Version) {
+			return nil, fmt.Errorf("refusing to remove version '%s' that is not a directory of the target directory", entry.Version)
+		}
+
+		fmt.Println(msg(messageRemovingVersion, entry.Version))
+		if err := os.RemoveAll(filepath.Join(dir, filepath.FromSlash(cfg.TargetDir), entry.Version)); err != nil {
+			return nil, fmt.Errorf("failed to remove version '%s': %w", entry.Version, err)
+		}
+
+		// The aliases of a removed version would otherwise keep serving a
+		// copy of it.
+		for _, alias := range entry.Aliases {
+			if !isVersionName(alias) {
+				return nil, fmt.Errorf("refusing to remove alias '%s' that is not a directory of the target directory", alias)
+			}
+
+			fmt.Println(msg(messageRemovingAlias, alias, entry.Version))
+			if err := os.RemoveAll(filepath.Join(dir, filepath.FromSlash(cfg.TargetDir), alias)); err != nil {
+				return nil, fmt.Errorf("failed to remove alias '%s': %w", alias, err)
+			}
+		}
+	}
+
+	return kept, nil
+}
+
+// versionRetention parses a retention policy into a function reporting
+// whether the version at the given position of the manifest is kept.
+func versionRetention(policy string) (func(index int, version string) bool, error) {
+	if count, err := strconv.Atoi(policy); err == nil {
+		if count < 1 {
+			return nil, fmt.Errorf("keep_versions must be at least 1, got %d", count)
+		}
+		return func(index int, _ string) bool { return index < count }, nil
+	}
+
+	type constraint struct {
+		operator string
+		version  string
+	}
+
+	var constraints []constraint
+	for _, field := range strings.Fields(policy) {
+		operator := field[:len(field)-len(strings.TrimLeft(field, "<>=!"))]
+		version := field[len(operator):]
+
+		switch operator {
+		case "<", "<=", ">", ">=", "=", "!=":
+		default:
+			return nil, fmt.Errorf("invalid keep_versions constraint '%s', expected e.g. >=2.0", field)
+		}
+		if version == "" {
+			return nil, fmt.Errorf("invalid keep_versions constraint '%s', expected e.g. >=2.0", field)
+		}
+
+		constraints = append(constraints, constraint{operator: operator, version: version})
+	}
+
+	return func(_ int, version string) bool {
+		for _, c := range constraints {
+			if !satisfiesConstraint(compareVersions(version, c.version), c.operator) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// satisfiesConstraint reports whether the result of comparing a version with
+// the version of a constraint satisfies its operator.
+func satisfiesConstraint(comparison int, operator string) bool {
+	switch operator {
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case ">":
+		return comparison > 0
+	case ">=":
+		return comparison >= 0
+	case "=":
+		return comparison == 0
+	default:
+		return comparison != 0
+	}
+}
+
+// compareVersions compares dotted versions such as v1.10.2 component by
+// component, numerically where both components are numbers.
+func compareVersions(a, b string) int {
+	left := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	right := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < max(len(left), len(right)); i++ {
+		var l, r string
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+
+		ln, lerr := strconv.Atoi(l)
+		rn, rerr := strconv.Atoi(r)
+		switch {
+		case lerr == nil && rerr == nil && ln != rn:
+			return cmp.Compare(ln, rn)
+		case (lerr != nil || rerr != nil) && l != r:
+			if l == "" {
+				return -1
+			}
+			if r == "" {
+				return 1
+			}
+			return strings.Compare(l, r)
+		}
+	}
+
+	return 0
+}
+
+// writeAliases replaces the alias directories in the target directory below
+// dir with copies of the published version, so that links such as /latest/
+// always lead to it.
+func writeAliases(cfg Config, dir string) error {
+	version := filepath.Join(dir, filepath.FromSlash(contentPath(cfg)))
+
+	for _, alias := range splitList(cfg.Aliases) {
+		aliasDir := filepath.Join(dir, filepath.FromSlash(cfg.TargetDir), alias)
+		if err := os.RemoveAll(aliasDir); err != nil {
+			return fmt.Errorf("failed to remove alias '%s': %w", alias, err)
+		}
+
+		if err := copyDirectory(version, aliasDir, nil); err != nil {
+			return fmt.Errorf("failed to copy version '%s' to alias '%s': %w", cfg.Version, alias, err)
+		}
+	}
+
+	return nil
+}
+
+// readVersions reads the versions manifest in the given schema. A missing
+// manifest has no versions.
+func readVersions(file, schema string) ([]versionEntry, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if schema == versionsSchemaList {
+		var versions []string
+		if err := json.Unmarshal(data, &versions); err != nil {
+			return nil, err
+		}
+
+		entries := make([]versionEntry, 0, len(versions))
+		for _, version := range versions {
+			entries = append(entries, versionEntry{Version: version, Title: version, Aliases: []string{}})
+		}
+		return entries, nil
+	}
+
+	var entries []versionEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// writeVersions writes the versions manifest in the given schema.
+func writeVersions(file, schema string, entries []versionEntry) error {
+	var manifest any = entries
+	if schema == versionsSchemaList {
+		versions := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			versions = append(versions, entry.Version)
+		}
+		manifest = versions
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isWithin reports whether path is root or lies below it. Both paths must be
+// absolute and cleaned.
+func isWithin(path, root string) bool {
+	relativePath, err := filepath.Rel(root, path)
+	return err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
+
+// checkWorkspaceContainment verifies that folder, and everything its symlinks
+// point to, lies within the workspace, so that misconfigured inputs cannot
+// publish arbitrary files from the runner.
+func checkWorkspaceContainment(folder, workspace string) error {
+	root, err := filepath.EvalSymlinks(workspace)
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace '%s': %w", workspace, err)
+	}
+
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+
+	resolvedFolder, err := filepath.EvalSymlinks(folder)
+	if err != nil {
+		return fmt.Errorf("failed to resolve folder '%s': %w", folder, err)
+	}
+
+	resolvedFolder, err = filepath.Abs(resolvedFolder)
+	if err != nil {
+		return err
+	}
+
+	if !isWithin(resolvedFolder, root) {
+		return fmt.Errorf("folder '%s' resolves to '%s', outside of the workspace '%s'", folder, resolvedFolder, workspace)
+	}
+
+	return filepath.Walk(resolvedFolder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve symlink '%s': %w", path, err)
+		}
+
+		target, err = filepath.Abs(target)
+		if err != nil {
+			return err
+		}
+
+		if !isWithin(target, root) {
+			return fmt.Errorf("symlink '%s' points to '%s', outside of the workspace '%s'", path, target, workspace)
+		}
+		return nil
+	})
+}